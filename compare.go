@@ -0,0 +1,136 @@
+package main
+
+import (
+	"io"
+	"slices"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/montanaflynn/claudette/internal/stats"
+)
+
+// markDelegate draws projects marked for comparison with a checkmark
+type markDelegate struct {
+	list.DefaultDelegate
+	marked map[string]bool // Marked project paths, shared with the model
+}
+
+func (d markDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if p, ok := item.(projectItem); ok && d.marked[p.path] {
+		item = markedItem{p}
+	}
+	d.DefaultDelegate.Render(w, m, index, item)
+}
+
+// markedItem puts the checkmark after the name, so filter matches are
+// still highlighted on the right characters
+type markedItem struct{ projectItem }
+
+func (i markedItem) Title() string { return i.name + " ✓" }
+
+type compareLoadedMsg struct {
+	usage   [2][]stats.GroupedUsage
+	warning string
+	err     error
+}
+
+// toggleMark marks or unmarks the selected project, allowing two at once
+func (m *model) toggleMark() tea.Cmd {
+	item, ok := m.list.SelectedItem().(projectItem)
+	if !ok {
+		return nil
+	}
+	if m.marked[item.path] {
+		delete(m.marked, item.path)
+		return nil
+	}
+	if len(m.marked) == 2 {
+		m.notice = "only two projects can be compared"
+		return clearNotice()
+	}
+	m.marked[item.path] = true
+	return nil
+}
+
+// openCompare opens the comparison of the two marked projects
+func (m *model) openCompare() tea.Cmd {
+	var projects []projectItem
+	for _, item := range m.list.Items() {
+		if p, ok := item.(projectItem); ok && m.marked[p.path] {
+			projects = append(projects, p)
+		}
+	}
+	if len(projects) != 2 {
+		m.notice = "mark two projects with space to compare"
+		return clearNotice()
+	}
+
+	clear(m.marked)
+	m.compared = [2]string{projects[0].name, projects[1].name}
+	m.selected = projects[0].name + " vs " + projects[1].name
+	m.currentView = compareView
+	return m.startLoading(loadCompare(projects[0].project(), projects[1].project()))
+}
+
+// loadCompare loads the daily usage of two projects, where one without a
+// path is All Projects
+func loadCompare(a, b stats.Project) tea.Cmd {
+	return func() tea.Msg {
+		var msg compareLoadedMsg
+		var partial error
+		for i, project := range []stats.Project{a, b} {
+			var usage []stats.GroupedUsage
+			var err error
+			if project.Path == "" {
+				usage, err = stats.LoadGroupedUsage("day")
+			} else {
+				usage, err = stats.LoadGroupedUsageForProject(project, "day")
+			}
+			if err != nil && !stats.IsPartial(err) {
+				return compareLoadedMsg{err: err}
+			}
+			if err != nil {
+				partial = err
+			}
+			msg.usage[i] = usage
+		}
+		msg.warning = partialWarning(partial)
+		return msg
+	}
+}
+
+// compareRows builds the comparison table, one column of period totals
+// for each project, followed by total token and cost rows
+func (m model) compareRows(formatNum func(int) string) ([]string, [][]string) {
+	totals := make(map[string][2]int)
+	var periods []string
+	var sums [2]int
+	var costs [2]float64
+	for side, usage := range m.compare {
+		for _, u := range usage {
+			pair, ok := totals[u.Period]
+			if !ok {
+				periods = append(periods, u.Period)
+			}
+			pair[side] += u.TotalTokens()
+			totals[u.Period] = pair
+			sums[side] += u.TotalTokens()
+			costs[side] += pricing.UsageCost(u)
+		}
+	}
+	slices.Sort(periods)
+	if CLI.Reverse {
+		slices.Reverse(periods)
+	}
+
+	var rows [][]string
+	for _, period := range periods {
+		pair := totals[period]
+		rows = append(rows, []string{period, formatNum(pair[0]), formatNum(pair[1])})
+	}
+	rows = append(rows,
+		[]string{"Total", formatNum(sums[0]), formatNum(sums[1])},
+		[]string{"Cost", stats.FormatCost(costs[0]), stats.FormatCost(costs[1])},
+	)
+	return []string{"Period", m.compared[0], m.compared[1]}, rows
+}
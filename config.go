@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// Config holds flag defaults read from the config file, keyed by flag name
+// (e.g. "group", "tz"). It is applied as a kong resolver so that explicit
+// flags and environment variables take precedence over it.
+type Config map[string]interface{}
+
+// configPath returns the location of the config file
+func configPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "claudette", "config.json")
+}
+
+// LoadConfig reads the config file, returning an empty config if it does not exist
+func LoadConfig() (Config, error) {
+	data, err := os.ReadFile(configPath())
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", configPath(), err)
+	}
+	return cfg, nil
+}
+
+func (c Config) lookup(name string) (interface{}, bool) {
+	if v, ok := c[name]; ok {
+		return v, true
+	}
+	v, ok := c[strings.ReplaceAll(name, "-", "_")]
+	return v, ok
+}
+
+// Validate rejects config keys that don't correspond to a flag, either
+// global or of a subcommand such as status's "every"
+func (c Config) Validate(app *kong.Application) error {
+	known := make(map[string]bool)
+	var addFlags func(node *kong.Node)
+	addFlags = func(node *kong.Node) {
+		for _, flag := range node.Flags {
+			known[flag.Name] = true
+			known[strings.ReplaceAll(flag.Name, "-", "_")] = true
+		}
+		for _, child := range node.Children {
+			addFlags(child)
+		}
+	}
+	addFlags(app.Node)
+	for key := range c {
+		if !known[key] {
+			return fmt.Errorf("unknown key %q in config %s", key, configPath())
+		}
+	}
+	return nil
+}
+
+// Resolve supplies a flag's value from the config unless one of its
+// environment variables is set
+func (c Config) Resolve(ctx *kong.Context, parent *kong.Path, flag *kong.Flag) (interface{}, error) {
+	for _, env := range flag.Tag.Envs {
+		if _, ok := os.LookupEnv(env); ok {
+			return nil, nil
+		}
+	}
+	if v, ok := c.lookup(flag.Name); ok {
+		return v, nil
+	}
+	return nil, nil
+}
+
+// cacheDir returns the directory for cached state, honoring --cache-dir
+func cacheDir() (string, error) {
+	if CLI.CacheDir != "" {
+		return CLI.CacheDir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "claudette"), nil
+}
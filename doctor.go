@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/montanaflynn/claudette/internal/stats"
+)
+
+// DoctorOutput is the JSON shape for `doctor`
+type DoctorOutput struct {
+	Roots           []RootOutput          `json:"roots"`
+	DuplicateFiles  []DuplicateFileOutput `json:"duplicate_files"`
+	MergedProjects  []MergedProjectOutput `json:"merged_projects"`
+	RawEvents       int                   `json:"raw_events"`
+	RawTokens       int                   `json:"raw_tokens"`
+	UniqueEvents    int                   `json:"unique_events"`
+	UniqueTokens    int                   `json:"unique_tokens"`
+	LoadedEvents    int                   `json:"loaded_events"`
+	LoadedTokens    int                   `json:"loaded_tokens"`
+	DuplicateEvents int                   `json:"duplicate_events"`
+	DuplicateTokens int                   `json:"duplicate_tokens"`
+}
+
+type RootOutput struct {
+	Root     string `json:"root"`
+	Projects int    `json:"projects"`
+	Files    int    `json:"files"`
+	Events   int    `json:"events"`
+}
+
+type DuplicateFileOutput struct {
+	Name  string   `json:"name"`
+	Paths []string `json:"paths"`
+	Sizes []int64  `json:"sizes"`
+}
+
+// MergedProjectOutput is a project whose logs are in several directories
+// written from the same working directory
+type MergedProjectOutput struct {
+	Name  string   `json:"name"`
+	Cwd   string   `json:"cwd"`
+	Paths []string `json:"paths"`
+}
+
+func showDoctor() error {
+	report, err := stats.CheckDedup()
+	if err != nil {
+		return err
+	}
+	projects, err := stats.ListProjects()
+	if err != nil {
+		return err
+	}
+	merged := []MergedProjectOutput{}
+	for _, p := range projects {
+		if len(p.Merged) > 0 {
+			merged = append(merged, MergedProjectOutput{Name: p.Name, Cwd: p.ActualPath, Paths: p.Dirs()})
+		}
+	}
+
+	if CLI.JSON {
+		output := DoctorOutput{
+			Roots:           make([]RootOutput, len(report.Roots)),
+			DuplicateFiles:  make([]DuplicateFileOutput, len(report.DuplicateFiles)),
+			MergedProjects:  merged,
+			RawEvents:       report.RawEvents,
+			RawTokens:       report.RawTokens,
+			UniqueEvents:    report.UniqueEvents,
+			UniqueTokens:    report.UniqueTokens,
+			LoadedEvents:    report.LoadedEvents,
+			LoadedTokens:    report.LoadedTokens,
+			DuplicateEvents: report.DuplicateEvents,
+			DuplicateTokens: report.DuplicateTokens,
+		}
+		for i, r := range report.Roots {
+			output.Roots[i] = RootOutput(r)
+		}
+		for i, d := range report.DuplicateFiles {
+			output.DuplicateFiles[i] = DuplicateFileOutput(d)
+		}
+		return encodeJSON(output)
+	}
+
+	fmt.Println("Search roots:")
+	for _, r := range report.Roots {
+		fmt.Printf("  %s: %d projects, %d files, %s events\n",
+			tildePath(r.Root), r.Projects, r.Files, stats.FormatTokens(r.Events))
+	}
+
+	fmt.Printf("\nDuplicate files: %d\n", len(report.DuplicateFiles))
+	for _, d := range report.DuplicateFiles {
+		fmt.Printf("  %s\n", d.Name)
+		for i, path := range d.Paths {
+			fmt.Printf("    %s (%d bytes)\n", tildePath(path), d.Sizes[i])
+		}
+	}
+
+	fmt.Printf("\nMerged projects: %d\n", len(merged))
+	for _, p := range merged {
+		fmt.Printf("  %s (%s)\n", p.Name, tildePath(p.Cwd))
+		for _, path := range p.Paths {
+			fmt.Printf("    %s\n", tildePath(path))
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("Events seen:     %s (%s tokens)\n", stats.FormatTokens(report.RawEvents), stats.FormatTokens(report.RawTokens))
+	fmt.Printf("After dedup:     %s (%s tokens)\n", stats.FormatTokens(report.UniqueEvents), stats.FormatTokens(report.UniqueTokens))
+	fmt.Printf("Dedup removed:   %s (%s tokens)\n", stats.FormatTokens(report.DuplicateEvents), stats.FormatTokens(report.DuplicateTokens))
+	fmt.Printf("Claudette loads: %s (%s tokens)\n", stats.FormatTokens(report.LoadedEvents), stats.FormatTokens(report.LoadedTokens))
+
+	fmt.Println()
+	switch diff := report.LoadedTokens - report.UniqueTokens; {
+	case diff == 0:
+		fmt.Println("OK: loaded totals match the deduplicated logs")
+	case diff < 0:
+		fmt.Printf("WARNING: loaded totals are %s tokens lower than the deduplicated logs;\n", stats.FormatTokens(-diff))
+		fmt.Println("projects sharing a name but not a working directory are only read from the first")
+	default:
+		fmt.Printf("WARNING: loaded totals are %s tokens higher than the deduplicated logs;\n", stats.FormatTokens(diff))
+		fmt.Println("some duplicate events are being counted twice")
+	}
+	return nil
+}
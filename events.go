@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/montanaflynn/claudette/internal/stats"
+)
+
+// showEvents prints every usage event, unaggregated, as a CSV row for
+// analysis elsewhere
+func showEvents(projectFilter string) error {
+	if !CLI.Events.CSV {
+		return errors.New("events are printed as CSV: pass --csv")
+	}
+
+	var events []stats.UsageEvent
+	var err error
+
+	if projectFilter == "" {
+		events, err = stats.LoadAllEvents()
+	} else {
+		project, findErr := findProject(projectFilter)
+		if findErr != nil {
+			return findErr
+		}
+		events, err = stats.LoadProjectEvents(*project)
+	}
+	if err = warnPartial(err); err != nil {
+		return err
+	}
+	slices.SortStableFunc(events, func(a, b stats.UsageEvent) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+
+	// Rows are written as they're formatted rather than collected first
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"timestamp", "project", "model", "input", "output", "cache_write", "cache_read", "event_id"})
+	for _, e := range events {
+		w.Write([]string{
+			e.Timestamp.Local().Format(time.RFC3339),
+			e.Project,
+			e.Model,
+			strconv.Itoa(e.InputTokens),
+			strconv.Itoa(e.OutputTokens),
+			strconv.Itoa(e.CacheCreation),
+			strconv.Itoa(e.CacheRead),
+			e.EventID,
+		})
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/montanaflynn/claudette/internal/stats"
+)
+
+func showHeatmap(projectFilter string) error {
+	var events []stats.UsageEvent
+	var err error
+
+	if projectFilter == "" {
+		events, err = stats.LoadAllEvents()
+	} else {
+		project, findErr := findProject(projectFilter)
+		if findErr != nil {
+			return findErr
+		}
+		events, err = stats.LoadProjectEvents(*project)
+	}
+	if err = warnPartial(err); err != nil {
+		return err
+	}
+
+	heatmap := stats.BuildHeatmap(events)
+
+	if CLI.JSON {
+		return encodeJSON(heatmap)
+	}
+
+	fmt.Println(renderHeatmap(heatmap))
+	return nil
+}
+
+// renderHeatmap draws a 7x24 grid shaded by token volume
+func renderHeatmap(h stats.Heatmap) string {
+	max := h.Max()
+
+	var b strings.Builder
+	b.WriteString("     ")
+	for hour := 0; hour < 24; hour++ {
+		if hour%3 == 0 {
+			b.WriteString(fmt.Sprintf("%-6d", hour))
+		}
+	}
+	b.WriteString("\n")
+
+	for i, day := range stats.HeatmapDays {
+		b.WriteString(fmt.Sprintf("%-5s", day.String()[:3]))
+		for hour := 0; hour < 24; hour++ {
+			b.WriteString(heatmapCell(h[i][hour], max))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n     less ")
+	for level := 0; level < heatmapLevels(); level++ {
+		b.WriteString(heatmapShade(level))
+	}
+	b.WriteString(" more")
+	return b.String()
+}
+
+func heatmapCell(value, max int) string {
+	if value == 0 || max == 0 {
+		return heatmapShade(0)
+	}
+	levels := heatmapLevels()
+	level := 1 + value*(levels-1)/max
+	if level >= levels {
+		level = levels - 1
+	}
+	return heatmapShade(level)
+}
+
+// heatmapLevels is how many shades the theme draws the heatmap with
+func heatmapLevels() int {
+	if styles.mono() {
+		return len(styles.HeatmapGlyphs)
+	}
+	return len(styles.HeatmapShades)
+}
+
+// heatmapShade draws one cell at a level from 0 to heatmapLevels()-1
+func heatmapShade(level int) string {
+	if styles.mono() {
+		return styles.HeatmapGlyphs[level]
+	}
+	return lipgloss.NewStyle().Background(styles.HeatmapShades[level]).Render("  ")
+}
@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EventCacheDir, when set, is where parsed log files are cached so that
+// unchanged logs aren't parsed again. The cache holds every event of a
+// file before deduplication, which is redone on each load, along with the
+// counts of lines that couldn't be parsed.
+var EventCacheDir string
+
+// cacheFormat is bumped whenever parsing changes what is cached, so older
+// entries are parsed again
+const cacheFormat = 7
+
+// cachedLog is the cache entry for one log file, valid while the format,
+// parse options, and the file's size and modification time, match
+type cachedLog struct {
+	Format      int
+	KeepZero    bool
+	MaxLineSize int
+	Path        string
+	Size        int64
+	ModTime     time.Time
+	Events      []fileEvent
+	Skipped     Skipped
+}
+
+// matches reports whether the entry is still valid for the log at path
+func (c cachedLog) matches(path string, info os.FileInfo) bool {
+	return c.Format == cacheFormat && c.KeepZero == KeepZero && c.MaxLineSize == MaxLineSize && c.Path == path &&
+		c.Size == info.Size() && c.ModTime.Equal(info.ModTime())
+}
+
+// readLogFile returns every usage event in a log file, from the cache when
+// the file hasn't changed since it was cached
+func readLogFile(path string) (logRead, error) {
+	if EventCacheDir == "" {
+		return readLogFileUncached(path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return logRead{}, err
+	}
+	cachePath := cacheEntryPath(path)
+	if entry, ok := loadCachedLog(cachePath); ok && entry.matches(path, info) {
+		return logRead{Events: entry.Events, Skipped: entry.Skipped}, nil
+	}
+
+	read, err := readLogFileUncached(path)
+	if err != nil {
+		return logRead{}, err
+	}
+	// A cache that can't be written only costs a re-parse next time
+	saveCachedLog(cachePath, cachedLog{
+		Format:      cacheFormat,
+		KeepZero:    KeepZero,
+		MaxLineSize: MaxLineSize,
+		Path:        path,
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+		Events:      read.Events,
+		Skipped:     read.Skipped,
+	})
+	return read, nil
+}
+
+func readLogFileUncached(path string) (logRead, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return logRead{}, err
+	}
+	defer file.Close()
+
+	return readLog(file, path), nil
+}
+
+// cacheEntryPath names a log's cache entry by a hash of its path
+func cacheEntryPath(path string) string {
+	hash := sha256.Sum256([]byte(path))
+	return filepath.Join(EventCacheDir, "events", hex.EncodeToString(hash[:])+".json")
+}
+
+func loadCachedLog(cachePath string) (cachedLog, bool) {
+	var entry cachedLog
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, false
+	}
+	return entry, true
+}
+
+func saveCachedLog(cachePath string, entry cachedLog) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0o755); err != nil {
+		return
+	}
+	// Write then rename so a concurrent reader never sees half an entry
+	tmp := cachePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	os.Rename(tmp, cachePath)
+}
@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Anthropic Console usage exports are a JSON document rather than JSONL:
+// either an array of usage rows, or an object whose "data" holds time
+// buckets with their rows under "results", as the usage report API
+// returns. Each row carries token counts for one model over a bucket.
+
+// isLogFile reports whether path is a usage log: Claude Code's JSONL or a
+// Console JSON export
+func isLogFile(path string) bool {
+	return strings.HasSuffix(path, ".jsonl") || strings.HasSuffix(path, ".json")
+}
+
+// logName strips a log's extension, naming it for --per-file
+func logName(source string) string {
+	return strings.TrimSuffix(source, filepath.Ext(source))
+}
+
+// readLog parses a log stream as a Console export when it is a ".json"
+// file or looks like one, and as JSONL otherwise
+func readLog(r io.Reader, source string) logRead {
+	reader := bufio.NewReader(r)
+	if strings.HasSuffix(source, ".json") || isConsoleExport(reader) {
+		return logRead{Events: readConsoleExport(reader, source)}
+	}
+	return readJSONL(reader, source)
+}
+
+// consoleStart matches the opening of a Console export: an array, or an
+// object whose first key is "data", which no JSONL log line starts with
+var consoleStart = regexp.MustCompile(`^\s*(\[|\{\s*"data"\s*:)`)
+
+// isConsoleExport sniffs the start of a stream without consuming it
+func isConsoleExport(reader *bufio.Reader) bool {
+	head, _ := reader.Peek(256)
+	return consoleStart.Match(head)
+}
+
+// readConsoleExport maps the rows of a Console export to usage events.
+// Rows without tokens or a usable time are skipped.
+func readConsoleExport(r io.Reader, source string) []fileEvent {
+	var doc interface{}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil
+	}
+
+	var parsed []fileEvent
+	add := func(row map[string]interface{}, bucketStart interface{}) {
+		event := consoleEvent(row, bucketStart)
+		if event == nil {
+			return
+		}
+		parsed = append(parsed, fileEvent{
+			Event:       *event,
+			Fingerprint: generateFingerprint(event, source, len(parsed)+1),
+		})
+	}
+
+	switch v := doc.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if row, ok := item.(map[string]interface{}); ok {
+				add(row, nil)
+			}
+		}
+	case map[string]interface{}:
+		buckets, _ := v["data"].([]interface{})
+		for _, item := range buckets {
+			bucket, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			results, _ := bucket["results"].([]interface{})
+			for _, result := range results {
+				if row, ok := result.(map[string]interface{}); ok {
+					add(row, bucket["starting_at"])
+				}
+			}
+		}
+	}
+	return parsed
+}
+
+// consoleEvent maps one Console row to a usage event. bucketStart, when
+// set, is the start of the time bucket the row belongs to.
+func consoleEvent(row map[string]interface{}, bucketStart interface{}) *UsageEvent {
+	input := getInt(row, "uncached_input_tokens")
+	if input == 0 {
+		input = getInt(row, "input_tokens")
+	}
+	cacheWrite, cache1h := extractCacheCreation(row)
+	event := &UsageEvent{
+		InputTokens:     input,
+		OutputTokens:    getInt(row, "output_tokens"),
+		CacheCreation:   cacheWrite,
+		CacheCreation1h: cache1h,
+		CacheRead:       getInt(row, "cache_read_input_tokens"),
+		Model:           getString(row, "model"),
+	}
+	if event.TotalTokens() == 0 && !KeepZero {
+		return nil
+	}
+
+	ts := consoleTimestamp(bucketStart)
+	for _, field := range []string{"starting_at", "date", "timestamp"} {
+		if !ts.IsZero() {
+			break
+		}
+		ts = consoleTimestamp(row[field])
+	}
+	if ts.IsZero() {
+		return nil
+	}
+	event.Timestamp = ts
+	return event
+}
+
+// consoleTimestamp parses a Console time, which may be a plain date taken
+// as local midnight
+func consoleTimestamp(val interface{}) time.Time {
+	if s, ok := val.(string); ok {
+		if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+			return t
+		}
+	}
+	if val == nil {
+		return time.Time{}
+	}
+	return parseTimestamp(val)
+}
@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"sort"
+	"time"
+)
+
+// windowCandidates are the usage limit window lengths
+// DetectSessionDuration chooses between
+var windowCandidates = []time.Duration{
+	1 * time.Hour, 2 * time.Hour, 3 * time.Hour, 4 * time.Hour,
+	5 * time.Hour, 6 * time.Hour, 7 * time.Hour, 8 * time.Hour,
+}
+
+const (
+	// resetPause is how long before a window ends work must have stopped
+	// for the break to look like waiting on a limit
+	resetPause = 30 * time.Minute
+	// resetSlack is how soon after a window ends work must resume
+	resetSlack = 15 * time.Minute
+	// minResets is how many waits for a reset are needed to name a length
+	minResets = 3
+)
+
+// DetectSessionDuration guesses the usage limit window length from when
+// work resumes. Someone who hits their limit stops well before the window
+// ends and starts again soon after it resets, so each candidate length is
+// scored by how many breaks end just after one of its resets. It returns 0
+// when no length has enough such breaks.
+func DetectSessionDuration(events []UsageEvent) time.Duration {
+	sorted := make([]UsageEvent, len(events))
+	copy(sorted, events)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	var best time.Duration
+	bestResets := 0
+	for _, d := range windowCandidates {
+		n := countResets(sorted, d)
+		// Ties go to the default, which most plans use
+		if n > bestResets || (n == bestResets && d == DefaultSessionDuration) {
+			best, bestResets = d, n
+		}
+	}
+	if bestResets < minResets {
+		return 0
+	}
+	return best
+}
+
+// countResets counts the blocks of the given length after which work
+// paused and then resumed just as the next block could start
+func countResets(events []UsageEvent, d time.Duration) int {
+	n := 0
+	var prev *SessionBlock
+	for _, b := range identifySessionBlocks(events, d) {
+		if b.IsGap {
+			prev = nil
+			continue
+		}
+		if prev != nil {
+			idle := prev.EndTime.Sub(prev.ActualEndTime)
+			resume := b.StartTime.Sub(prev.EndTime)
+			if idle >= resetPause && resume >= 0 && resume < resetSlack {
+				n++
+			}
+		}
+		prev = &b
+	}
+	return n
+}
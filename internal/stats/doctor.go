@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// RootReport summarizes the logs found under one search root
+type RootReport struct {
+	Root     string
+	Projects int
+	Files    int
+	Events   int
+}
+
+// DuplicateFile is a log that exists, by project directory and relative
+// path, under more than one search root
+type DuplicateFile struct {
+	Name  string   // Project directory and path within it
+	Paths []string // Full path under each root
+	Sizes []int64  // Size of each copy, which differ when a copy is stale
+}
+
+// DedupReport describes how logs overlap across search roots and how much
+// the event fingerprint dedup removes
+type DedupReport struct {
+	Roots           []RootReport
+	DuplicateFiles  []DuplicateFile
+	RawEvents       int // Events counted with no cross-file dedup
+	RawTokens       int
+	UniqueEvents    int // Events left after fingerprint dedup
+	UniqueTokens    int
+	LoadedEvents    int // Events the regular loaders return
+	LoadedTokens    int
+	DuplicateEvents int
+	DuplicateTokens int
+}
+
+// CheckDedup scans every project directory under every search root,
+// without the name-based project dedup ListProjects applies, and reports
+// duplicate files and events along with their token impact
+func CheckDedup() (*DedupReport, error) {
+	report := &DedupReport{}
+	dedupeCache := make(map[string]bool)
+	copies := make(map[string]*DuplicateFile)
+	var names []string
+
+	for _, root := range SearchRoots() {
+		rootReport := RootReport{Root: root}
+
+		for _, projectPath := range projectDirs(root) {
+			projectName := projectDisplayName(projectPath, findActualPath(projectPath))
+			if excluded(projectName) {
+				continue
+			}
+			rootReport.Projects++
+
+			walkLogs(projectPath, func(path string, info os.FileInfo, err error) error {
+				if err != nil || !recentLog(path, info) {
+					return nil
+				}
+				rel, _ := filepath.Rel(root, path)
+				if copies[rel] == nil {
+					copies[rel] = &DuplicateFile{Name: rel}
+					names = append(names, rel)
+				}
+				copies[rel].Paths = append(copies[rel].Paths, path)
+				copies[rel].Sizes = append(copies[rel].Sizes, info.Size())
+
+				// Parse once on its own and once against everything seen
+				// so far; the difference is what dedup drops
+				raw, err := parseJSONLFile(path, make(map[string]bool), projectName)
+				if err != nil {
+					return nil
+				}
+				unique, _ := parseJSONLFile(path, dedupeCache, projectName)
+
+				rootReport.Files++
+				rootReport.Events += len(raw)
+				report.RawEvents += len(raw)
+				report.RawTokens += sumTokens(raw)
+				report.UniqueEvents += len(unique)
+				report.UniqueTokens += sumTokens(unique)
+				return nil
+			})
+		}
+
+		report.Roots = append(report.Roots, rootReport)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		if len(copies[name].Paths) > 1 {
+			report.DuplicateFiles = append(report.DuplicateFiles, *copies[name])
+		}
+	}
+	report.DuplicateEvents = report.RawEvents - report.UniqueEvents
+	report.DuplicateTokens = report.RawTokens - report.UniqueTokens
+
+	loaded, err := LoadAllEvents()
+	if err != nil && !IsPartial(err) {
+		return nil, err
+	}
+	report.LoadedEvents = len(loaded)
+	report.LoadedTokens = sumTokens(loaded)
+
+	return report, nil
+}
+
+func sumTokens(events []UsageEvent) int {
+	total := 0
+	for _, e := range events {
+		total += e.TotalTokens()
+	}
+	return total
+}
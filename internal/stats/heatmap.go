@@ -0,0 +1,65 @@
+package stats
+
+import "time"
+
+// Heatmap holds total tokens bucketed by local day of week and hour of day.
+// Rows follow HeatmapDays (Monday first), columns are hours (0-23).
+type Heatmap [7][24]int
+
+// HeatmapDays lists the weekday of each heatmap row, Monday first like
+// --group weekday
+var HeatmapDays = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday,
+	time.Friday, time.Saturday, time.Sunday,
+}
+
+// BuildHeatmap buckets events by the local weekday and hour they occurred
+func BuildHeatmap(events []UsageEvent) Heatmap {
+	var h Heatmap
+	for _, e := range events {
+		t := e.Timestamp.Local()
+		row := (int(t.Weekday()) + 6) % 7 // Monday is row 0
+		h[row][t.Hour()] += e.TotalTokens()
+	}
+	return h
+}
+
+// Max returns the largest bucket value
+func (h *Heatmap) Max() int {
+	max := 0
+	for _, day := range h {
+		for _, v := range day {
+			if v > max {
+				max = v
+			}
+		}
+	}
+	return max
+}
+
+// ByHour totals each hour of day across the week
+func (h *Heatmap) ByHour() [24]int {
+	var hours [24]int
+	for _, day := range h {
+		for hour, v := range day {
+			hours[hour] += v
+		}
+	}
+	return hours
+}
+
+// BusiestHour returns the hour of day with the most tokens and its share of
+// all tokens. ok is false when there are no tokens.
+func BusiestHour(hours [24]int) (hour int, share float64, ok bool) {
+	total := 0
+	for h, v := range hours {
+		total += v
+		if v > hours[hour] {
+			hour = h
+		}
+	}
+	if total == 0 {
+		return 0, 0, false
+	}
+	return hour, float64(hours[hour]) / float64(total), true
+}
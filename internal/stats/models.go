@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"sort"
+	"time"
+)
+
+// ModelSummary holds lifetime usage for a single raw model identifier
+type ModelSummary struct {
+	Model         string // Raw identifier from the logs
+	ShortName     string // Normalized name used in aggregations
+	Input         int
+	Output        int
+	CacheCreate   int
+	CacheCreate1h int
+	CacheRead     int
+	FirstSeen     time.Time
+	LastSeen      time.Time
+}
+
+// TotalTokens returns all tokens used by the model
+func (m *ModelSummary) TotalTokens() int {
+	return m.Input + m.Output + m.CacheCreate + m.CacheRead
+}
+
+// SummarizeModels totals usage per raw model identifier, most used first
+func SummarizeModels(events []UsageEvent) []ModelSummary {
+	byModel := make(map[string]*ModelSummary)
+
+	for _, e := range events {
+		model := e.Model
+		if model == "" {
+			model = "unknown"
+		}
+
+		s, ok := byModel[model]
+		if !ok {
+			short := shortModelName(e.Model)
+			if short == "" {
+				short = "unknown"
+			}
+			s = &ModelSummary{Model: model, ShortName: short, FirstSeen: e.Timestamp, LastSeen: e.Timestamp}
+			byModel[model] = s
+		}
+
+		s.Input += e.InputTokens
+		s.Output += e.OutputTokens
+		s.CacheCreate += e.CacheCreation
+		s.CacheCreate1h += e.CacheCreation1h
+		s.CacheRead += e.CacheRead
+		if e.Timestamp.Before(s.FirstSeen) {
+			s.FirstSeen = e.Timestamp
+		}
+		if e.Timestamp.After(s.LastSeen) {
+			s.LastSeen = e.Timestamp
+		}
+	}
+
+	result := make([]ModelSummary, 0, len(byModel))
+	for _, s := range byModel {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalTokens() != result[j].TotalTokens() {
+			return result[i].TotalTokens() > result[j].TotalTokens()
+		}
+		return result[i].Model < result[j].Model
+	})
+	return result
+}
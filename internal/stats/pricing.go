@@ -0,0 +1,306 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ModelPricing holds USD rates per million tokens
+type ModelPricing struct {
+	Input        float64 `json:"input"`
+	Output       float64 `json:"output"`
+	CacheWrite   float64 `json:"cache_write"`    // 5-minute cache writes
+	CacheWrite1h float64 `json:"cache_write_1h"` // 1-hour cache writes, CacheWrite when unset
+	CacheRead    float64 `json:"cache_read"`
+}
+
+// Pricing maps normalized model names (as produced by aggregation, e.g.
+// "sonnet-4-5") or bare families ("sonnet") to their rates
+type Pricing map[string]ModelPricing
+
+// DefaultPricing returns Anthropic's published API list prices
+func DefaultPricing() Pricing {
+	return Pricing{
+		"opus-4-5":   {Input: 5, Output: 25, CacheWrite: 6.25, CacheWrite1h: 10, CacheRead: 0.50},
+		"opus-4-1":   {Input: 15, Output: 75, CacheWrite: 18.75, CacheWrite1h: 30, CacheRead: 1.50},
+		"opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheWrite1h: 30, CacheRead: 1.50},
+		"opus-3":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheWrite1h: 30, CacheRead: 1.50},
+		"opus":       {Input: 5, Output: 25, CacheWrite: 6.25, CacheWrite1h: 10, CacheRead: 0.50},
+		"sonnet-4-5": {Input: 3, Output: 15, CacheWrite: 3.75, CacheWrite1h: 6, CacheRead: 0.30},
+		"sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheWrite1h: 6, CacheRead: 0.30},
+		"sonnet-3-7": {Input: 3, Output: 15, CacheWrite: 3.75, CacheWrite1h: 6, CacheRead: 0.30},
+		"sonnet-3-5": {Input: 3, Output: 15, CacheWrite: 3.75, CacheWrite1h: 6, CacheRead: 0.30},
+		"sonnet":     {Input: 3, Output: 15, CacheWrite: 3.75, CacheWrite1h: 6, CacheRead: 0.30},
+		"haiku-4-5":  {Input: 1, Output: 5, CacheWrite: 1.25, CacheWrite1h: 2, CacheRead: 0.10},
+		"haiku-3-5":  {Input: 0.80, Output: 4, CacheWrite: 1, CacheWrite1h: 1.60, CacheRead: 0.08},
+		"haiku-3":    {Input: 0.25, Output: 1.25, CacheWrite: 0.30, CacheWrite1h: 0.50, CacheRead: 0.03},
+		"haiku":      {Input: 1, Output: 5, CacheWrite: 1.25, CacheWrite1h: 2, CacheRead: 0.10},
+	}
+}
+
+// PricingEnv names the environment variable that can hold pricing
+// overrides as inline JSON, for injecting negotiated rates as a secret
+// rather than writing them to a file
+const PricingEnv = "CLAUDETTE_PRICING_JSON"
+
+// LoadPricing returns the default pricing, overridden by entries from the
+// JSON in PricingEnv when it is set, or else from the JSON file at path
+// when one is given
+func LoadPricing(path string) (Pricing, error) {
+	pricing := DefaultPricing()
+
+	var data []byte
+	source := "pricing file " + path
+	if inline := os.Getenv(PricingEnv); inline != "" {
+		data, source = []byte(inline), PricingEnv
+	} else if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	} else {
+		return pricing, nil
+	}
+
+	var overrides Pricing
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", source, err)
+	}
+	for model, rates := range overrides {
+		pricing[model] = rates
+	}
+	return pricing, nil
+}
+
+// Rates returns the pricing for a model, accepting raw or normalized names
+// and falling back to the model family
+func (p Pricing) Rates(model string) (ModelPricing, bool) {
+	if rates, ok := p[model]; ok {
+		return rates, true
+	}
+	short := shortModelName(model)
+	if rates, ok := p[short]; ok {
+		return rates, true
+	}
+	for _, family := range []string{"opus", "sonnet", "haiku"} {
+		if strings.Contains(short, family) {
+			rates, ok := p[family]
+			return rates, ok
+		}
+	}
+	return ModelPricing{}, false
+}
+
+// Cost returns the USD cost of the given token counts for a model
+func (p Pricing) Cost(model string, input, output, cacheWrite, cacheRead int) float64 {
+	rates, ok := p.Rates(model)
+	if !ok {
+		return 0
+	}
+	return (float64(input)*rates.Input +
+		float64(output)*rates.Output +
+		float64(cacheWrite)*rates.CacheWrite +
+		float64(cacheRead)*rates.CacheRead) / 1_000_000
+}
+
+// EventCost returns the USD cost of a single event, pricing 1-hour cache
+// writes at their own rate
+func (p Pricing) EventCost(e UsageEvent) float64 {
+	return p.EventCostBreakdown(e).Total()
+}
+
+// CostBreakdown splits a USD cost by token type
+type CostBreakdown struct {
+	Input  float64
+	Output float64
+	Cache  float64 // Cache writes and reads
+}
+
+// Total returns the combined cost
+func (c CostBreakdown) Total() float64 {
+	return c.Input + c.Output + c.Cache
+}
+
+// Add returns the sum of two breakdowns
+func (c CostBreakdown) Add(o CostBreakdown) CostBreakdown {
+	return CostBreakdown{Input: c.Input + o.Input, Output: c.Output + o.Output, Cache: c.Cache + o.Cache}
+}
+
+// EventCostBreakdown returns the USD cost of a single event by token type
+func (p Pricing) EventCostBreakdown(e UsageEvent) CostBreakdown {
+	rates, ok := p.Rates(e.Model)
+	if !ok {
+		return CostBreakdown{}
+	}
+	rate1h := rates.CacheWrite1h
+	if rate1h == 0 {
+		rate1h = rates.CacheWrite
+	}
+	return CostBreakdown{
+		Input:  float64(e.InputTokens) * rates.Input / 1_000_000,
+		Output: float64(e.OutputTokens) * rates.Output / 1_000_000,
+		Cache: (float64(e.CacheCreation-e.CacheCreation1h)*rates.CacheWrite +
+			float64(e.CacheCreation1h)*rate1h +
+			float64(e.CacheRead)*rates.CacheRead) / 1_000_000,
+	}
+}
+
+// PeriodCost is the cost of the usage in one period
+type PeriodCost struct {
+	Period string
+	CostBreakdown
+}
+
+// PeriodCosts totals event costs by time period (hour, day, week, month,
+// year), in period order
+func (p Pricing) PeriodCosts(events []UsageEvent, groupBy string) []PeriodCost {
+	var result []PeriodCost
+	index := make(map[string]int)
+	for _, e := range events {
+		period := formatPeriod(e.Timestamp.Local(), groupBy)
+		i, ok := index[period]
+		if !ok {
+			i = len(result)
+			index[period] = i
+			result = append(result, PeriodCost{Period: period})
+		}
+		result[i].CostBreakdown = result[i].CostBreakdown.Add(p.EventCostBreakdown(e))
+	}
+	order := periodOrder(groupBy)
+	slices.SortStableFunc(result, func(a, b PeriodCost) int {
+		return order(a.Period, b.Period)
+	})
+	return result
+}
+
+// EventsCost returns the total USD cost of a set of events
+func (p Pricing) EventsCost(events []UsageEvent) float64 {
+	total := 0.0
+	for _, e := range events {
+		total += p.EventCost(e)
+	}
+	return total
+}
+
+// SummaryCost returns the USD cost of a model's lifetime usage
+func (p Pricing) SummaryCost(s ModelSummary) float64 {
+	return p.EventCost(UsageEvent{
+		Model:           s.Model,
+		InputTokens:     s.Input,
+		OutputTokens:    s.Output,
+		CacheCreation:   s.CacheCreate,
+		CacheCreation1h: s.CacheCreate1h,
+		CacheRead:       s.CacheRead,
+	})
+}
+
+// CostPer1kOutput returns cost per thousand output tokens, the effective
+// price of each unit of work, or 0 when there was no output
+func CostPer1kOutput(cost float64, output int) float64 {
+	if output == 0 {
+		return 0
+	}
+	return cost / float64(output) * 1000
+}
+
+// UsageCost returns the USD cost of a period's usage, priced per model
+func (p Pricing) UsageCost(u GroupedUsage) float64 {
+	total := 0.0
+	for name, mu := range u.ByModel {
+		total += p.EventCost(UsageEvent{
+			Model:           name,
+			InputTokens:     mu.Input,
+			OutputTokens:    mu.Output,
+			CacheCreation:   mu.CacheCreate,
+			CacheCreation1h: mu.CacheCreate1h,
+			CacheRead:       mu.CacheRead,
+		})
+	}
+	return total
+}
+
+// CacheSavings returns how much less a period's cache reads cost than the
+// same tokens would have as uncached input, priced per model
+func CacheSavings(u GroupedUsage, pricing Pricing) float64 {
+	total := 0.0
+	for name, mu := range u.ByModel {
+		if rates, ok := pricing.Rates(name); ok {
+			total += float64(mu.CacheRead) * (rates.Input - rates.CacheRead) / 1_000_000
+		}
+	}
+	return total
+}
+
+// ProjectionDays is how many recent days ProjectMonthlyCost averages over
+var ProjectionDays = 7
+
+// MonthToDateCost returns the cost of the days so far in now's month
+func MonthToDateCost(daily []DailyUsage, pricing Pricing, now time.Time) float64 {
+	month := now.Format("2006-01")
+	total := 0.0
+	for _, d := range daily {
+		if strings.HasPrefix(d.Date, month) {
+			total += pricing.UsageCost(d.AsGrouped())
+		}
+	}
+	return total
+}
+
+// AverageDailyCost returns the mean cost per day over the ProjectionDays
+// ending today, counting days without usage as zero
+func AverageDailyCost(daily []DailyUsage, pricing Pricing, now time.Time) float64 {
+	days := ProjectionDays
+	if days < 1 {
+		days = 1
+	}
+	from := now.AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+	to := now.Format("2006-01-02")
+
+	total := 0.0
+	for _, d := range daily {
+		if d.Date >= from && d.Date <= to {
+			total += pricing.UsageCost(d.AsGrouped())
+		}
+	}
+	return total / float64(days)
+}
+
+// ProjectMonthlyCost estimates the month's total cost: the cost so far
+// plus the recent daily average for each day left in the month
+func ProjectMonthlyCost(daily []DailyUsage, pricing Pricing, now time.Time) float64 {
+	daysInMonth := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location()).Day()
+	remaining := daysInMonth - now.Day()
+	return MonthToDateCost(daily, pricing, now) + AverageDailyCost(daily, pricing, now)*float64(remaining)
+}
+
+// DayCost is the estimated cost of one local day
+type DayCost struct {
+	Date string // YYYY-MM-DD
+	Cost float64
+}
+
+// RecentDailyCosts returns the cost of each of the given number of days
+// ending today, oldest first, with days without usage as zero so the
+// series is continuous
+func RecentDailyCosts(daily []DailyUsage, pricing Pricing, now time.Time, days int) []DayCost {
+	byDate := make(map[string]float64)
+	for _, d := range daily {
+		byDate[d.Date] = pricing.UsageCost(d.AsGrouped())
+	}
+
+	result := make([]DayCost, days)
+	for i := range result {
+		date := now.AddDate(0, 0, i-(days-1)).Format("2006-01-02")
+		result[i] = DayCost{Date: date, Cost: byDate[date]}
+	}
+	return result
+}
+
+// FormatCost formats a USD amount for display
+func FormatCost(cost float64) string {
+	return fmt.Sprintf("$%.2f", cost)
+}
@@ -0,0 +1,39 @@
+package stats
+
+// Progress reports how far a load has got
+type Progress struct {
+	FilesDone  int
+	FilesTotal int
+	Events     int
+}
+
+// ProgressFunc, when set, is called after each log file is parsed so that
+// long loads can report progress
+var ProgressFunc func(Progress)
+
+// progressTracker accumulates progress across the files of a single load
+type progressTracker struct {
+	progress Progress
+}
+
+// newProgressTracker starts a load of the given number of log files,
+// returning nil when nobody is listening for progress
+func newProgressTracker(files int) *progressTracker {
+	if ProgressFunc == nil {
+		return nil
+	}
+
+	t := &progressTracker{progress: Progress{FilesTotal: files}}
+	ProgressFunc(t.progress)
+	return t
+}
+
+// fileDone records a parsed file and its events
+func (t *progressTracker) fileDone(events int) {
+	if t == nil || ProgressFunc == nil {
+		return
+	}
+	t.progress.FilesDone++
+	t.progress.Events += events
+	ProgressFunc(t.progress)
+}
@@ -5,13 +5,16 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -25,18 +28,30 @@ type Project struct {
 	Name       string
 	Path       string
 	ActualPath string
+	// Merged are further log directories written from the same working
+	// directory, e.g. before the project was renamed
+	Merged []string
+}
+
+// Dirs returns every directory holding the project's logs
+func (p Project) Dirs() []string {
+	return append([]string{p.Path}, p.Merged...)
 }
 
 // UsageEvent represents a single token usage record
 type UsageEvent struct {
-	Timestamp     time.Time
-	InputTokens   int
-	OutputTokens  int
-	CacheCreation int
-	CacheRead     int
-	Model         string
-	Project       string
-	EventID       string
+	Timestamp       time.Time
+	InputTokens     int
+	OutputTokens    int
+	CacheCreation   int
+	CacheCreation1h int // Portion of CacheCreation written to the 1-hour cache
+	CacheRead       int
+	Model           string
+	Project         string
+	EventID         string
+	SessionID       string
+	Role            string // Message role, e.g. "assistant", when logged
+	SourceFile      string // Basename of the log file, or "stdin"
 }
 
 // TotalTokens returns all tokens (input + output + cache)
@@ -75,6 +90,25 @@ func (b *SessionBlock) NonCacheTokens() int {
 	return b.InputTokens + b.OutputTokens
 }
 
+// CacheHitRatio returns the share of input-side tokens served from cache
+func (b *SessionBlock) CacheHitRatio() float64 {
+	inputSide := b.InputTokens + b.CacheCreation + b.CacheRead
+	if inputSide == 0 {
+		return 0
+	}
+	return float64(b.CacheRead) / float64(inputSide)
+}
+
+// CacheReadShare returns the share of all tokens that were cache reads, a
+// rough measure of how cheap the block was for its size
+func (b *SessionBlock) CacheReadShare() float64 {
+	total := b.TotalTokens()
+	if total == 0 {
+		return 0
+	}
+	return float64(b.CacheRead) / float64(total)
+}
+
 // BurnRate holds rate calculations
 type BurnRate struct {
 	TokensPerMinute          float64
@@ -88,10 +122,29 @@ type DailyUsage struct {
 	InputTotal       int
 	OutputTotal      int
 	CacheCreateTotal int
+	CacheCreate1h    int // Portion of CacheCreateTotal written to the 1-hour cache
 	CacheReadTotal   int
+	ZeroEvents       int // Events without any tokens, only kept with KeepZero
+	Requests         int // Usage records, roughly one per API request
 	ByModel          map[string]*ModelUsage
 }
 
+// AsGrouped returns the day as a GroupedUsage with the date as its period
+func (d DailyUsage) AsGrouped() GroupedUsage {
+	return GroupedUsage{
+		Period:           d.Date,
+		Models:           d.Models,
+		InputTotal:       d.InputTotal,
+		OutputTotal:      d.OutputTotal,
+		CacheCreateTotal: d.CacheCreateTotal,
+		CacheCreate1h:    d.CacheCreate1h,
+		CacheReadTotal:   d.CacheReadTotal,
+		ZeroEvents:       d.ZeroEvents,
+		Requests:         d.Requests,
+		ByModel:          d.ByModel,
+	}
+}
+
 // GroupedUsage holds usage aggregated by a time period
 type GroupedUsage struct {
 	Period           string
@@ -99,51 +152,308 @@ type GroupedUsage struct {
 	InputTotal       int
 	OutputTotal      int
 	CacheCreateTotal int
+	CacheCreate1h    int // Portion of CacheCreateTotal written to the 1-hour cache
 	CacheReadTotal   int
+	ZeroEvents       int // Events without any tokens, only kept with KeepZero
+	Requests         int // Usage records, roughly one per API request
 	ByModel          map[string]*ModelUsage
 }
 
+// TotalTokens returns all tokens in the period
+func (g *GroupedUsage) TotalTokens() int {
+	return g.InputTotal + g.OutputTotal + g.CacheCreateTotal + g.CacheReadTotal
+}
+
+// SumUsage combines periods into a single total, merging per-model counts
+func SumUsage(usage []GroupedUsage) GroupedUsage {
+	sum := GroupedUsage{
+		Period:  "Total",
+		ByModel: make(map[string]*ModelUsage),
+	}
+
+	for _, u := range usage {
+		sum.InputTotal += u.InputTotal
+		sum.OutputTotal += u.OutputTotal
+		sum.CacheCreateTotal += u.CacheCreateTotal
+		sum.CacheCreate1h += u.CacheCreate1h
+		sum.CacheReadTotal += u.CacheReadTotal
+		sum.ZeroEvents += u.ZeroEvents
+		sum.Requests += u.Requests
+
+		for name, mu := range u.ByModel {
+			if _, ok := sum.ByModel[name]; !ok {
+				sum.ByModel[name] = &ModelUsage{Model: name}
+				sum.Models = append(sum.Models, name)
+			}
+			sum.ByModel[name].Input += mu.Input
+			sum.ByModel[name].Output += mu.Output
+			sum.ByModel[name].CacheCreate += mu.CacheCreate
+			sum.ByModel[name].CacheCreate1h += mu.CacheCreate1h
+			sum.ByModel[name].CacheRead += mu.CacheRead
+			sum.ByModel[name].Requests += mu.Requests
+		}
+	}
+
+	sort.Strings(sum.Models)
+	return sum
+}
+
+// SplitMinTokens separates periods with at least min tokens from those
+// below it, keeping their order
+func SplitMinTokens(usage []GroupedUsage, min int) (kept, dropped []GroupedUsage) {
+	for _, u := range usage {
+		if u.TotalTokens() < min {
+			dropped = append(dropped, u)
+		} else {
+			kept = append(kept, u)
+		}
+	}
+	return kept, dropped
+}
+
+// UsageDiff is how one period's usage changed between two sets of usage.
+// Before or After is nil when the period appears on one side only.
+type UsageDiff struct {
+	Period string
+	Before *GroupedUsage
+	After  *GroupedUsage
+}
+
+// Delta returns the change in total tokens
+func (d UsageDiff) Delta() int {
+	delta := 0
+	if d.After != nil {
+		delta += d.After.TotalTokens()
+	}
+	if d.Before != nil {
+		delta -= d.Before.TotalTokens()
+	}
+	return delta
+}
+
+// DiffUsage pairs up periods by label and returns those added, removed or
+// with different token counts, in period order
+func DiffUsage(before, after []GroupedUsage) []UsageDiff {
+	byPeriod := make(map[string]*UsageDiff)
+	var periods []string
+	diff := func(period string) *UsageDiff {
+		d, ok := byPeriod[period]
+		if !ok {
+			d = &UsageDiff{Period: period}
+			byPeriod[period] = d
+			periods = append(periods, period)
+		}
+		return d
+	}
+	for i := range before {
+		diff(before[i].Period).Before = &before[i]
+	}
+	for i := range after {
+		diff(after[i].Period).After = &after[i]
+	}
+	slices.SortFunc(periods, comparePeriods)
+
+	var changed []UsageDiff
+	for _, period := range periods {
+		d := byPeriod[period]
+		if d.Before != nil && d.After != nil && sameTokens(*d.Before, *d.After) {
+			continue
+		}
+		changed = append(changed, *d)
+	}
+	return changed
+}
+
+func sameTokens(a, b GroupedUsage) bool {
+	return a.InputTotal == b.InputTotal && a.OutputTotal == b.OutputTotal &&
+		a.CacheCreateTotal == b.CacheCreateTotal && a.CacheCreate1h == b.CacheCreate1h &&
+		a.CacheReadTotal == b.CacheReadTotal
+}
+
+// UsageStats describes how token totals vary across periods
+type UsageStats struct {
+	Max       int
+	Min       int
+	Avg       int
+	Median    int
+	MaxPeriod string
+	MinPeriod string
+}
+
+// PeriodStats finds the busiest and quietest periods along with the mean
+// and median tokens per period
+func PeriodStats(usage []GroupedUsage) UsageStats {
+	var s UsageStats
+	if len(usage) == 0 {
+		return s
+	}
+
+	totals := make([]int, len(usage))
+	sum := 0
+	for i, u := range usage {
+		total := u.TotalTokens()
+		totals[i] = total
+		sum += total
+		if i == 0 || total > s.Max {
+			s.Max, s.MaxPeriod = total, u.Period
+		}
+		if i == 0 || total < s.Min {
+			s.Min, s.MinPeriod = total, u.Period
+		}
+	}
+	s.Avg = sum / len(usage)
+
+	sort.Ints(totals)
+	mid := len(totals) / 2
+	if len(totals)%2 == 0 {
+		s.Median = (totals[mid-1] + totals[mid]) / 2
+	} else {
+		s.Median = totals[mid]
+	}
+
+	return s
+}
+
+// Streaks returns the longest run of consecutive days with usage and the
+// current run, which ends today or, before today's first use, yesterday
+func Streaks(daily []DailyUsage, now time.Time) (current, longest int) {
+	active := make(map[string]bool)
+	var dates []string
+	for _, d := range daily {
+		tokens := d.InputTotal + d.OutputTotal + d.CacheCreateTotal + d.CacheReadTotal
+		if tokens > 0 && !active[d.Date] {
+			active[d.Date] = true
+			dates = append(dates, d.Date)
+		}
+	}
+	sort.Strings(dates)
+
+	run := 0
+	var prev time.Time
+	for _, date := range dates {
+		t, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			continue
+		}
+		if run > 0 && t.Equal(prev.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		prev = t
+		longest = max(longest, run)
+	}
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !active[day.Format("2006-01-02")] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for active[day.Format("2006-01-02")] {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+	return current, longest
+}
+
 // ModelUsage holds per-model token counts
 type ModelUsage struct {
-	Model       string
-	Input       int
-	Output      int
-	CacheCreate int
-	CacheRead   int
+	Model         string
+	Input         int
+	Output        int
+	CacheCreate   int
+	CacheCreate1h int // Portion of CacheCreate written to the 1-hour cache
+	CacheRead     int
+	Requests      int
 }
 
-// ListProjects finds all Claude Code projects
-func ListProjects() ([]Project, error) {
-	var projects []Project
-	seen := make(map[string]bool)
+// Roots overrides the directories scanned for projects. When empty,
+// DefaultRoots is used.
+var Roots []string
+
+// ActiveThreshold is how long a block may go without activity and still
+// count as active. Zero means the session duration.
+var ActiveThreshold time.Duration
+
+// PerFile attributes each event to its log file, named without the .jsonl
+// extension, instead of its project, so every file is aggregated on its own
+var PerFile bool
+
+// ExcludeProjects holds glob patterns, such as "tmp-*", for project names
+// to leave out of every listing and load
+var ExcludeProjects []string
+
+// excluded reports whether the project name matches an ExcludeProjects
+// pattern
+func excluded(name string) bool {
+	for _, pattern := range ExcludeProjects {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
 
-	roots := []string{
+// DefaultRoots returns the directories Claude Code writes project logs to
+func DefaultRoots() []string {
+	return []string{
 		filepath.Join(os.Getenv("HOME"), ".claude", "projects"),
 		filepath.Join(os.Getenv("HOME"), ".config", "claude", "projects"),
 	}
+}
 
-	for _, root := range roots {
-		entries, err := os.ReadDir(root)
-		if err != nil {
-			continue
-		}
+// ProjectDepth is how many directories below each search root project
+// directories are, for layouts that nest them, such as <org>/<repo>.
+// Values under 1 mean 1, the usual layout.
+var ProjectDepth = 1
 
-		for _, entry := range entries {
-			if !entry.IsDir() {
-				continue
-			}
+// SearchRoots returns the directories ListProjects scans
+func SearchRoots() []string {
+	if len(Roots) > 0 {
+		return Roots
+	}
+	return DefaultRoots()
+}
+
+// ListProjects finds all Claude Code projects
+func ListProjects() ([]Project, error) {
+	var projects []Project
+	byName := make(map[string]int)       // Index into projects
+	byActualPath := make(map[string]int) // Index into projects
+	byAlias := make(map[string]bool)     // Names given by an alias
 
-			path := filepath.Join(root, entry.Name())
-			name := projectNameFromPath(entry.Name())
+	for _, root := range SearchRoots() {
+		for _, path := range projectDirs(root) {
+			actualPath := findActualPath(path)
+			name := projectDisplayName(path, actualPath)
+			_, aliased := ProjectAliases[filepath.Base(path)]
 
-			if seen[name] {
+			if excluded(name) {
+				continue
+			}
+			// Directories logged from the same working directory, or
+			// given the same alias, are one project, so their usage isn't
+			// split
+			if i, ok := byActualPath[actualPath]; ok && actualPath != "" {
+				projects[i].Merged = append(projects[i].Merged, path)
 				continue
 			}
-			seen[name] = true
+			if i, ok := byName[name]; ok {
+				// An alias takes in any directory going by its name,
+				// whichever of the two is found first
+				if aliased || byAlias[name] {
+					projects[i].Merged = append(projects[i].Merged, path)
+				}
+				continue
+			}
+			byName[name] = len(projects)
 
-			actualPath := findActualPath(path)
+			if aliased {
+				byAlias[name] = true
+			}
 			if actualPath == "" {
 				actualPath = path // Fallback
+			} else {
+				byActualPath[actualPath] = len(projects)
 			}
 
 			projects = append(projects, Project{
@@ -161,37 +471,112 @@ func ListProjects() ([]Project, error) {
 	return projects, nil
 }
 
+// ProjectForDir returns the project whose working directory is dir or
+// contains it, so running inside a repo's subdirectory still finds the
+// repo. The deepest match wins.
+func ProjectForDir(projects []Project, dir string) (Project, bool) {
+	dir = filepath.Clean(dir)
+	var found Project
+	ok := false
+	for _, p := range projects {
+		actual := filepath.Clean(p.ActualPath)
+		if dir != actual && !strings.HasPrefix(dir, actual+string(filepath.Separator)) {
+			continue
+		}
+		if !ok || len(actual) > len(found.ActualPath) {
+			found, ok = p, true
+		}
+	}
+	return found, ok
+}
+
+// projectDirs returns the project directories ProjectDepth levels below
+// root, or none when root can't be read
+func projectDirs(root string) []string {
+	dirs := []string{root}
+	for range max(ProjectDepth, 1) {
+		var next []string
+		for _, dir := range dirs {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				if isDirEntry(dir, entry) {
+					next = append(next, filepath.Join(dir, entry.Name()))
+				}
+			}
+		}
+		dirs = next
+	}
+	return dirs
+}
+
+// cwdLogs is how many logs findActualPath reads before giving up. Logs
+// that record a working directory all do, so if the first few don't, the
+// rest won't either.
+const cwdLogs = 3
+
+// findActualPath returns the working directory recorded in a project's
+// logs, looking in subdirectories too for layouts that nest sessions
 func findActualPath(projectPath string) string {
-	entries, err := os.ReadDir(projectPath)
+	var cwd string
+	read := 0
+	walkLogs(projectPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".jsonl") {
+			return nil
+		}
+		if cwd = logCwd(path); cwd != "" {
+			return filepath.SkipAll
+		}
+		if read++; read == cwdLogs {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return cwd
+}
+
+// logCwd returns the first working directory recorded in a log
+func logCwd(path string) string {
+	file, err := os.Open(path)
 	if err != nil {
 		return ""
 	}
+	defer file.Close()
 
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
 			continue
 		}
 
-		path := filepath.Join(projectPath, entry.Name())
-		file, err := os.Open(path)
-		if err != nil {
-			continue
+		if cwd := getString(record, "cwd"); cwd != "" {
+			return cwd
 		}
-		defer file.Close()
-
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			var record map[string]interface{}
-			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
-				continue
-			}
+	}
+	return ""
+}
 
-			if cwd := getString(record, "cwd"); cwd != "" {
-				return cwd
-			}
+// ProjectAliases renames projects, keyed by the name of their directory
+// under the search root, such as "-home-user-src-api". Directories given
+// the same alias are merged into one project.
+var ProjectAliases map[string]string
+
+// projectDisplayName returns the display name for a project directory: its
+// alias if it has one, or else the basename of its working directory when
+// the logs recorded one
+func projectDisplayName(projectPath, actualPath string) string {
+	if alias, ok := ProjectAliases[filepath.Base(projectPath)]; ok {
+		return alias
+	}
+	if actualPath != "" {
+		if base := filepath.Base(actualPath); base != "." && base != string(filepath.Separator) {
+			return base
 		}
 	}
-	return ""
+	return projectNameFromPath(filepath.Base(projectPath))
 }
 
 func projectNameFromPath(dirName string) string {
@@ -202,18 +587,95 @@ func projectNameFromPath(dirName string) string {
 	return dirName
 }
 
+// HasUsage reports whether any log in the project contains a usage event,
+// stopping at the first one found
+func HasUsage(projectPath string) bool {
+	found := false
+	walkLogs(projectPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !isLogFile(path) {
+			return nil
+		}
+		if strings.HasSuffix(path, ".json") {
+			read, _ := readLogFileUncached(path)
+			for _, p := range read.Events {
+				if plausibleTimestamp(p.Event.Timestamp) {
+					found = true
+					return filepath.SkipAll
+				}
+			}
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer file.Close()
+
+		reader := bufio.NewReader(file)
+		for {
+			line, _, err := readLine(reader)
+			if len(line) > 0 {
+				if event, _ := parseLine(line, ""); event != nil && plausibleTimestamp(event.Timestamp) {
+					found = true
+					return filepath.SkipAll
+				}
+			}
+			if err != nil {
+				return nil
+			}
+		}
+	})
+	return found
+}
+
+// ProjectError records why a project's logs could not be fully read
+type ProjectError struct {
+	Project string
+	Err     error
+}
+
+func (e ProjectError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Project, e.Err)
+}
+
+func (e ProjectError) Unwrap() error { return e.Err }
+
+// PartialError is returned alongside usable results when some projects
+// could not be fully read, so the totals are incomplete
+type PartialError struct {
+	Projects []ProjectError
+}
+
+func (e *PartialError) Error() string {
+	if len(e.Projects) == 1 {
+		return "1 project could not be fully read"
+	}
+	return fmt.Sprintf("%d projects could not be fully read", len(e.Projects))
+}
+
+// IsPartial reports whether err only signals incomplete results
+func IsPartial(err error) bool {
+	var partial *PartialError
+	return errors.As(err, &partial)
+}
+
 // LoadSessionBlocks loads and groups usage into session blocks
 func LoadSessionBlocks(project Project, sessionDuration time.Duration) ([]SessionBlock, error) {
-	events, err := parseProjectEvents(project.Path)
-	if err != nil {
-		return nil, err
-	}
+	events, err := parseProjectEvents(project)
+	return identifySessionBlocks(events, sessionDuration), err
+}
 
-	return identifySessionBlocks(events, sessionDuration), nil
+// SessionBlocksForEvents groups already-loaded, sorted events into session
+// blocks
+func SessionBlocksForEvents(events []UsageEvent, sessionDuration time.Duration) []SessionBlock {
+	return identifySessionBlocks(events, sessionDuration)
 }
 
-// LoadAllSessionBlocks loads session blocks across ALL projects
-func LoadAllSessionBlocks(sessionDuration time.Duration) ([]SessionBlock, error) {
+// LoadAllEvents loads usage events across all projects, deduplicated and
+// sorted by timestamp. Projects that fail to parse are reported in a
+// *PartialError returned with the events that could be read
+func LoadAllEvents() ([]UsageEvent, error) {
 	projects, err := ListProjects()
 	if err != nil {
 		return nil, err
@@ -221,11 +683,14 @@ func LoadAllSessionBlocks(sessionDuration time.Duration) ([]SessionBlock, error)
 
 	var allEvents []UsageEvent
 	dedupeCache := make(map[string]bool)
+	dirs, tracker := findLogDirs(projects...)
 
-	for _, project := range projects {
-		events, err := parseProjectEventsWithDedupe(project.Path, dedupeCache)
+	var partial PartialError
+	for _, dir := range dirs {
+		// Keep whatever was readable from a failing project
+		events, err := parseLogDir(dir, dedupeCache, tracker)
 		if err != nil {
-			continue
+			partial.Projects = append(partial.Projects, ProjectError{Project: dir.project, Err: err})
 		}
 		allEvents = append(allEvents, events...)
 	}
@@ -234,35 +699,165 @@ func LoadAllSessionBlocks(sessionDuration time.Duration) ([]SessionBlock, error)
 		return allEvents[i].Timestamp.Before(allEvents[j].Timestamp)
 	})
 
-	return identifySessionBlocks(allEvents, sessionDuration), nil
+	if len(partial.Projects) > 0 {
+		return allEvents, &partial
+	}
+	return allEvents, nil
 }
 
-func parseProjectEventsWithDedupe(projectPath string, dedupeCache map[string]bool) ([]UsageEvent, error) {
-	var allEvents []UsageEvent
-	projectName := projectNameFromPath(filepath.Base(projectPath))
+// LoadEventsByProject loads usage events across all projects as
+// LoadAllEvents does, along with each project's own events, keyed by its
+// name, as LoadProjectEvents would load them. A log copied between
+// projects counts towards each of them but once in the combined events.
+// Every log is read once.
+func LoadEventsByProject() ([]UsageEvent, map[string][]UsageEvent, error) {
+	projects, err := ListProjects()
+	if err != nil {
+		return nil, nil, err
+	}
 
-	err := filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
+	var allEvents []UsageEvent
+	byProject := make(map[string][]UsageEvent)
+	dedupeCache := make(map[string]bool)
+	projectCaches := make(map[string]map[string]bool)
+	dirs, tracker := findLogDirs(projects...)
+
+	var partial PartialError
+	for _, dir := range dirs {
+		projectCache, ok := projectCaches[dir.project]
+		if !ok {
+			projectCache = make(map[string]bool)
+			projectCaches[dir.project] = projectCache
+		}
+		err := readLogDir(dir, tracker, func(path string, read logRead) int {
+			// Each pass counts what it skips, so gets its own copy
+			own := read
+			byProject[dir.project] = append(byProject[dir.project], dedupeEvents(&own, path, projectCache, dir.project)...)
+			events := dedupeEvents(&read, path, dedupeCache, dir.project)
+			allEvents = append(allEvents, events...)
+			return len(events)
+		})
 		if err != nil {
-			return nil
+			partial.Projects = append(partial.Projects, ProjectError{Project: dir.project, Err: err})
 		}
-		if info.IsDir() || !strings.HasSuffix(path, ".jsonl") {
-			return nil
+	}
+
+	sort.Slice(allEvents, func(i, j int) bool {
+		return allEvents[i].Timestamp.Before(allEvents[j].Timestamp)
+	})
+	for _, events := range byProject {
+		sort.Slice(events, func(i, j int) bool {
+			return events[i].Timestamp.Before(events[j].Timestamp)
+		})
+	}
+
+	if len(partial.Projects) > 0 {
+		return allEvents, byProject, &partial
+	}
+	return allEvents, byProject, nil
+}
+
+// LoadProjectEvents loads usage events for a single project, sorted by timestamp
+func LoadProjectEvents(project Project) ([]UsageEvent, error) {
+	return parseProjectEvents(project)
+}
+
+// LoadAllSessionBlocks loads session blocks across ALL projects
+func LoadAllSessionBlocks(sessionDuration time.Duration) ([]SessionBlock, error) {
+	allEvents, err := LoadAllEvents()
+	if err != nil && !IsPartial(err) {
+		return nil, err
+	}
+
+	return identifySessionBlocks(allEvents, sessionDuration), err
+}
+
+// DropSmallSessions removes sessions with fewer than min tokens from
+// chronological blocks. Gaps left next to each other are merged into one
+// spanning the dropped sessions.
+func DropSmallSessions(blocks []SessionBlock, min int) []SessionBlock {
+	var kept []SessionBlock
+	for _, b := range blocks {
+		if !b.IsGap && b.TotalTokens() < min {
+			continue
+		}
+		if b.IsGap && len(kept) > 0 && kept[len(kept)-1].IsGap {
+			kept[len(kept)-1].EndTime = b.EndTime
+			continue
 		}
+		kept = append(kept, b)
+	}
+	return kept
+}
 
-		events, err := parseJSONLFile(path, dedupeCache, projectName)
-		if err != nil {
-			return nil
+// logDir is one of a project's directories and the logs found in it
+type logDir struct {
+	project string
+	logs    []string
+	err     error // The first error walking the directory
+}
+
+// findLogDirs walks each of the projects' directories once, listing the
+// logs to parse, and returns a tracker for the progress of parsing them
+func findLogDirs(projects ...Project) ([]logDir, *progressTracker) {
+	var dirs []logDir
+	files := 0
+	for _, project := range projects {
+		for _, dirPath := range project.Dirs() {
+			dir := logDir{project: project.Name}
+			walkLogs(dirPath, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					if dir.err == nil {
+						dir.err = err
+					}
+					return nil
+				}
+				if recentLog(path, info) {
+					dir.logs = append(dir.logs, path)
+				}
+				return nil
+			})
+			dirs = append(dirs, dir)
+			files += len(dir.logs)
 		}
+	}
+	return dirs, newProgressTracker(files)
+}
 
+// parseLogDir parses every log found in a directory, attributing the
+// events to its project. It carries on past unreadable files, returning
+// the first error, from the walk or a file, with the events that could be
+// read.
+func parseLogDir(dir logDir, dedupeCache map[string]bool, tracker *progressTracker) ([]UsageEvent, error) {
+	var allEvents []UsageEvent
+	err := readLogDir(dir, tracker, func(path string, read logRead) int {
+		events := dedupeEvents(&read, path, dedupeCache, dir.project)
 		allEvents = append(allEvents, events...)
-		return nil
+		return len(events)
 	})
+	return allEvents, err
+}
 
-	if err != nil {
-		return nil, err
+// readLogDir reads every log found in a directory, handing each to keep,
+// which returns how many events it kept for progress. It carries on past
+// unreadable files, returning the first error, from the walk or a file.
+func readLogDir(dir logDir, tracker *progressTracker, keep func(path string, read logRead) int) error {
+	firstErr := dir.err
+
+	for _, path := range dir.logs {
+		read, err := readLogFile(path)
+		if err != nil {
+			// An unreadable file is done too, so progress reaches the total
+			tracker.fileDone(0)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		tracker.fileDone(keep(path, read))
 	}
 
-	return allEvents, nil
+	return firstErr
 }
 
 // GetActiveBlock returns the currently active session block, if any
@@ -275,105 +870,232 @@ func GetActiveBlock(blocks []SessionBlock) *SessionBlock {
 	return nil
 }
 
-// parseProjectEvents recursively parses all JSONL files in a project
-func parseProjectEvents(projectPath string) ([]UsageEvent, error) {
+// parseProjectEvents recursively parses all JSONL files in each of a
+// project's directories
+func parseProjectEvents(project Project) ([]UsageEvent, error) {
 	var allEvents []UsageEvent
+	var err error
 	dedupeCache := make(map[string]bool)
-	projectName := projectNameFromPath(filepath.Base(projectPath))
-
-	err := filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
+	dirs, tracker := findLogDirs(project)
+	for _, dir := range dirs {
+		events, dirErr := parseLogDir(dir, dedupeCache, tracker)
+		if err == nil {
+			err = dirErr
 		}
-		if info.IsDir() || !strings.HasSuffix(path, ".jsonl") {
-			return nil
-		}
-
-		events, err := parseJSONLFile(path, dedupeCache, projectName)
-		if err != nil {
-			return nil
-		}
-
 		allEvents = append(allEvents, events...)
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
 	}
 
 	sort.Slice(allEvents, func(i, j int) bool {
 		return allEvents[i].Timestamp.Before(allEvents[j].Timestamp)
 	})
 
+	if err != nil {
+		return allEvents, &PartialError{Projects: []ProjectError{{Project: filepath.Base(project.Path), Err: err}}}
+	}
 	return allEvents, nil
 }
 
-// parseJSONLFile parses a single JSONL file
+// ParseEvents parses usage events from a stream, such as stdin,
+// deduplicating within the stream. The stream may be JSONL or a Console
+// JSON export.
+func ParseEvents(r io.Reader, projectName string) []UsageEvent {
+	read := readLog(r, "stdin")
+	events := dedupeEvents(&read, "stdin", make(map[string]bool), projectName)
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+	return events
+}
+
+// parseJSONLFile parses a single JSONL file, from the event cache when
+// it is enabled and the file is unchanged
 func parseJSONLFile(path string, dedupeCache map[string]bool, projectName string) ([]UsageEvent, error) {
-	file, err := os.Open(path)
+	read, err := readLogFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	return dedupeEvents(&read, path, dedupeCache, projectName), nil
+}
 
-	var events []UsageEvent
-	reader := bufio.NewReader(file)
-	var partial []byte
+// parseJSONL parses usage events from a JSONL stream. source names the
+// stream, by its full path for a file, for fingerprinting events that
+// carry no ID.
+func parseJSONL(r io.Reader, source string, dedupeCache map[string]bool, projectName string) []UsageEvent {
+	read := readJSONL(r, source)
+	return dedupeEvents(&read, source, dedupeCache, projectName)
+}
+
+// fileEvent is a parsed event with the fingerprint used to deduplicate it
+type fileEvent struct {
+	Event       UsageEvent
+	Fingerprint string
+}
+
+// logRead is everything parsed from one log: its events, and counts of
+// what couldn't be parsed
+type logRead struct {
+	Events  []fileEvent
+	Skipped Skipped
+}
+
+// readJSONL parses every usage event in a stream, without deduplicating
+// or attributing them to a project.
+//
+// Malformed lines are skipped on their own so they can't affect the records
+// that follow. A final line without a trailing newline may still be being
+// written; it is used if it parses and otherwise skipped and counted as
+// partial. Once the log changes it's read again from the start, so the line
+// is used when it's complete.
+func readJSONL(r io.Reader, source string) logRead {
+	var read logRead
+	reader := bufio.NewReader(r)
+	lineNum := 0
 
 	for {
-		line, err := reader.ReadBytes('\n')
+		line, tooLong, err := readLine(reader)
 		if err != nil && err != io.EOF {
 			break
 		}
-
-		if len(partial) > 0 {
-			line = append(partial, line...)
-			partial = nil
+		if tooLong {
+			lineNum++
+			read.Skipped.Lines++
+		} else if len(line) > 0 {
+			lineNum++
+			event, undated := parseLine(line, "")
+			if event != nil {
+				read.Events = append(read.Events, fileEvent{
+					Event:       *event,
+					Fingerprint: generateFingerprint(event, source, lineNum),
+				})
+			} else if undated {
+				read.Skipped.Undated++
+			} else if err == io.EOF && !json.Valid(line) {
+				read.Skipped.Partial++
+			}
+		}
+		if err == io.EOF {
+			break
 		}
+	}
 
-		if len(line) == 0 {
-			if err == io.EOF {
-				break
+	return read
+}
+
+// MaxLineSize is the longest log line, in bytes, that is parsed. Longer
+// lines, such as a corrupt log missing its newlines, are skipped without
+// being held in memory. Zero means no limit.
+var MaxLineSize = 8 << 20
+
+// readLine reads up to and including the next newline. A line longer than
+// MaxLineSize is read through to its end but returned as nil, with tooLong
+// set.
+func readLine(reader *bufio.Reader) (line []byte, tooLong bool, err error) {
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong {
+			line = append(line, chunk...)
+			if MaxLineSize > 0 && len(line) > MaxLineSize {
+				line, tooLong = nil, true
 			}
-			continue
 		}
+		if err != bufio.ErrBufferFull {
+			return line, tooLong, err
+		}
+	}
+}
 
-		var record map[string]interface{}
-		if jsonErr := json.Unmarshal(line, &record); jsonErr != nil {
-			partial = line
-			if err == io.EOF {
-				break
-			}
+// dedupeEvents drops events whose fingerprint is already in dedupeCache,
+// recording the rest, and attributes them to projectName and, with UTC,
+// moves them to UTC. With CarryModel, events without a model take the one
+// before them in the stream. Events with implausible timestamps are
+// dropped and added to read's counts, which are then recorded for source.
+// It runs after every read, cached or not, so totals don't depend on the
+// cache's state.
+func dedupeEvents(read *logRead, source string, dedupeCache map[string]bool, projectName string) []UsageEvent {
+	var events []UsageEvent
+	lastModel := ""
+	for _, p := range read.Events {
+		event := p.Event
+		if !plausibleTimestamp(event.Timestamp) {
+			read.Skipped.Timestamps++
 			continue
 		}
-
-		event := extractUsageEvent(record, projectName)
-		if event == nil {
-			if err == io.EOF {
-				break
+		if CarryModel {
+			if event.Model == "" {
+				event.Model = lastModel
 			}
-			continue
+			lastModel = event.Model
 		}
 
-		// Deduplicate
-		fp := generateFingerprint(event)
-		if dedupeCache[fp] {
-			if err == io.EOF {
-				break
-			}
+		if dedupeCache[p.Fingerprint] {
 			continue
 		}
-		dedupeCache[fp] = true
+		dedupeCache[p.Fingerprint] = true
+
+		event.Project = projectName
+		event.SourceFile = filepath.Base(source)
+		if UTC {
+			event.Timestamp = event.Timestamp.UTC()
+		}
+		if PerFile && source != "stdin" {
+			event.Project = logName(event.SourceFile)
+		}
+		events = append(events, event)
+	}
+	recordSkipped(source, read.Skipped)
+	return events
+}
+
+// Skipped counts the lines and events of a log left out of its totals
+type Skipped struct {
+	Lines      int // Longer than MaxLineSize
+	Timestamps int // Dated before 2023 or more than a day in the future
+	Undated    int // Usage without a timestamp in a recognized format
+	Partial    int // Final lines without a newline that didn't parse
+}
+
+// skippedByLog holds the counts from the last read of each log, keyed by
+// its path, so reading a log again replaces its counts rather than adding
+// to them
+var skippedByLog = struct {
+	sync.Mutex
+	logs map[string]Skipped
+}{logs: make(map[string]Skipped)}
+
+func recordSkipped(source string, skipped Skipped) {
+	skippedByLog.Lock()
+	defer skippedByLog.Unlock()
+	skippedByLog.logs[source] = skipped
+}
 
-		events = append(events, *event)
+// SkippedTotals totals what was skipped across the logs read so far,
+// counting each log once however many times it was read
+func SkippedTotals() Skipped {
+	skippedByLog.Lock()
+	defer skippedByLog.Unlock()
+	var total Skipped
+	for _, s := range skippedByLog.logs {
+		total.Lines += s.Lines
+		total.Timestamps += s.Timestamps
+		total.Undated += s.Undated
+		total.Partial += s.Partial
+	}
+	return total
+}
 
-		if err == io.EOF {
-			break
-		}
+// parseLine decodes a single JSONL line into a usage event, returning nil
+// for malformed or non-usage lines. undated reports a usage record skipped
+// for lacking a timestamp in any format parseTimestamp knows.
+func parseLine(line []byte, projectName string) (event *UsageEvent, undated bool) {
+	var record map[string]interface{}
+	if err := json.Unmarshal(line, &record); err != nil {
+		return nil, false
 	}
-
-	return events, nil
+	event = extractUsageEvent(record, projectName)
+	if event == nil && findUsage(record) != nil && extractTimestamp(record).IsZero() {
+		return nil, true
+	}
+	return event, false
 }
 
 func extractUsageEvent(record map[string]interface{}, projectName string) *UsageEvent {
@@ -395,34 +1117,155 @@ func extractUsageEvent(record map[string]interface{}, projectName string) *Usage
 		}
 	}
 
-	event := &UsageEvent{
-		Timestamp:     ts,
-		InputTokens:   getInt(usage, "input_tokens"),
-		OutputTokens:  getInt(usage, "output_tokens"),
-		CacheCreation: getInt(usage, "cache_creation_input_tokens"),
-		CacheRead:     getInt(usage, "cache_read_input_tokens"),
-		Model:         model,
-		Project:       projectName,
-		EventID:       findEventID(record),
-	}
+	cacheCreation, cacheCreation1h := extractCacheCreation(usage)
 
-	if event.TotalTokens() == 0 {
+	event := &UsageEvent{
+		Timestamp:       ts,
+		InputTokens:     getInt(usage, "input_tokens"),
+		OutputTokens:    getInt(usage, "output_tokens"),
+		CacheCreation:   cacheCreation,
+		CacheCreation1h: cacheCreation1h,
+		CacheRead:       getInt(usage, "cache_read_input_tokens"),
+		Model:           model,
+		Project:         projectName,
+		EventID:         findEventID(record),
+		SessionID:       findSessionID(record),
+		Role:            findRole(record),
+	}
+
+	if event.TotalTokens() == 0 && !KeepZero {
 		return nil
 	}
 
 	return event
 }
 
+// sumUsageObjects adds up the numeric fields of several usage objects,
+// including nested objects such as cache_creation
+func sumUsageObjects(usages []map[string]interface{}) map[string]interface{} {
+	sum := make(map[string]interface{})
+	nested := make(map[string][]map[string]interface{})
+
+	for _, usage := range usages {
+		for key, val := range usage {
+			switch v := val.(type) {
+			case float64:
+				total, _ := sum[key].(float64)
+				sum[key] = total + v
+			case map[string]interface{}:
+				nested[key] = append(nested[key], v)
+			default:
+				if _, ok := sum[key]; !ok {
+					sum[key] = val
+				}
+			}
+		}
+	}
+	for key, objects := range nested {
+		sum[key] = sumUsageObjects(objects)
+	}
+
+	return sum
+}
+
+// extractCacheCreation returns total cache write tokens and the portion
+// written to the 1-hour cache, when the usage breaks it out either as a
+// nested cache_creation object or a suffixed field
+func extractCacheCreation(usage map[string]interface{}) (total, oneHour int) {
+	total = getInt(usage, "cache_creation_input_tokens")
+	oneHour = getInt(usage, "cache_creation_input_tokens_1h")
+
+	if breakdown, ok := usage["cache_creation"].(map[string]interface{}); ok {
+		fiveMin := getInt(breakdown, "ephemeral_5m_input_tokens")
+		oneHour = getInt(breakdown, "ephemeral_1h_input_tokens")
+		if total == 0 {
+			total = fiveMin + oneHour
+		}
+	} else if oneHour > 0 {
+		fiveMin := getInt(usage, "cache_creation_input_tokens_5m")
+		if total == 0 || total == fiveMin {
+			total = fiveMin + oneHour
+		}
+	}
+
+	return total, oneHour
+}
+
+// findUsage locates the usage object in a record: on the message, on
+// blocks of the message content, or at the top level.
+//
+// Usage on content blocks is each block's own share, so several are
+// summed. Usage on the message and at the top level are instead running
+// totals for the whole message: streaming logs write the usage seen when
+// the message started on the message and the final usage, from the last
+// message_delta event, at the top level. When a record has both, the final
+// counts win, see latestUsage.
 func findUsage(record map[string]interface{}) map[string]interface{} {
+	var snapshots []map[string]interface{}
 	if msg, ok := record["message"].(map[string]interface{}); ok {
 		if usage, ok := msg["usage"].(map[string]interface{}); ok {
-			return usage
+			snapshots = append(snapshots, usage)
+		} else if content, ok := msg["content"].([]interface{}); ok {
+			var usages []map[string]interface{}
+			for _, item := range content {
+				block, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if usage, ok := block["usage"].(map[string]interface{}); ok {
+					usages = append(usages, usage)
+				}
+			}
+			switch len(usages) {
+			case 0:
+			case 1:
+				snapshots = append(snapshots, usages[0])
+			default:
+				snapshots = append(snapshots, sumUsageObjects(usages))
+			}
 		}
 	}
 	if usage, ok := record["usage"].(map[string]interface{}); ok {
-		return usage
+		snapshots = append(snapshots, usage)
+	}
+
+	switch len(snapshots) {
+	case 0:
+		return nil
+	case 1:
+		return snapshots[0]
+	default:
+		return latestUsage(snapshots)
 	}
-	return nil
+}
+
+// latestUsage combines running totals of one message's usage, taking the
+// largest value of each count. Counts only grow as a message streams, so
+// that is the final count, and a count missing from the final usage, as
+// input tokens are from older message_delta events, is kept from earlier.
+func latestUsage(snapshots []map[string]interface{}) map[string]interface{} {
+	latest := make(map[string]interface{})
+	nested := make(map[string][]map[string]interface{})
+
+	for _, usage := range snapshots {
+		for key, val := range usage {
+			switch v := val.(type) {
+			case float64:
+				if prev, ok := latest[key].(float64); !ok || v > prev {
+					latest[key] = v
+				}
+			case map[string]interface{}:
+				nested[key] = append(nested[key], v)
+			default:
+				latest[key] = val
+			}
+		}
+	}
+	for key, objects := range nested {
+		latest[key] = latestUsage(objects)
+	}
+
+	return latest
 }
 
 func extractTimestamp(record map[string]interface{}) time.Time {
@@ -437,52 +1280,117 @@ func extractTimestamp(record map[string]interface{}) time.Time {
 	return time.Time{}
 }
 
+// earliestTimestamp predates any Claude Code log; anything older, or more
+// than a day in the future, comes from a corrupt line
+var earliestTimestamp = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// plausibleTimestamp is checked on every load rather than when a log is
+// parsed, so a cached event isn't judged against when it was cached
+func plausibleTimestamp(ts time.Time) bool {
+	return !ts.Before(earliestTimestamp) && !ts.After(time.Now().Add(24*time.Hour))
+}
+
+// timestampLayouts are the string formats parseTimestamp accepts, tried in
+// order. Those without a zone are taken as UTC, as Claude Code logs are.
+var timestampLayouts = []string{
+	time.RFC3339Nano, // Also matches RFC3339, with or without fractional seconds
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
 func parseTimestamp(val interface{}) time.Time {
 	switch v := val.(type) {
 	case string:
-		if t, err := time.Parse(time.RFC3339, v); err == nil {
-			return t
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
 		}
-		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
-			return t
+		// Epoch times written as strings
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return unixTimestamp(n)
 		}
 	case float64:
-		if v > 1e12 {
-			return time.UnixMilli(int64(v))
-		}
-		return time.Unix(int64(v), 0)
+		return unixTimestamp(int64(v))
 	case int64:
-		if v > 1e12 {
-			return time.UnixMilli(v)
-		}
-		return time.Unix(v, 0)
+		return unixTimestamp(v)
 	}
 	return time.Time{}
 }
 
+// unixTimestamp reads an epoch time in seconds, milliseconds, microseconds
+// or nanoseconds, telling them apart by size
+func unixTimestamp(n int64) time.Time {
+	switch {
+	case n >= 1e18:
+		return time.Unix(0, n)
+	case n >= 1e15:
+		return time.UnixMicro(n)
+	case n > 1e12:
+		return time.UnixMilli(n)
+	}
+	return time.Unix(n, 0)
+}
+
 func findEventID(record map[string]interface{}) string {
 	for _, field := range []string{"id", "request_id", "message_id"} {
 		if id := getString(record, field); id != "" {
 			return id
 		}
 	}
+	requestID := getString(record, "requestId")
 	if msg, ok := record["message"].(map[string]interface{}); ok {
 		if id := getString(msg, "id"); id != "" {
+			// Claude Code logs the API request ID beside the message ID;
+			// like ccusage, identify the event by the pair
+			if requestID != "" {
+				return id + ":" + requestID
+			}
+			return id
+		}
+	}
+	return requestID
+}
+
+// findRole returns the message role, which is on the message in Claude
+// Code logs and may be at the top level elsewhere
+func findRole(record map[string]interface{}) string {
+	if msg, ok := record["message"].(map[string]interface{}); ok {
+		if role := getString(msg, "role"); role != "" {
+			return role
+		}
+	}
+	return getString(record, "role")
+}
+
+func findSessionID(record map[string]interface{}) string {
+	for _, field := range []string{"sessionId", "session_id"} {
+		if id := getString(record, field); id != "" {
 			return id
 		}
 	}
 	return ""
 }
 
-func generateFingerprint(event *UsageEvent) string {
+// generateFingerprint identifies an event for deduplication. Events without
+// an ID are additionally keyed by the full path of their source file and
+// their line, so distinct requests with identical timestamps and token
+// counts aren't merged, even from same-named logs in different projects.
+func generateFingerprint(event *UsageEvent, source string, line int) string {
 	data := fmt.Sprintf("%d:%d:%s:%s",
 		event.Timestamp.UnixMilli(),
 		event.TotalTokens(),
 		event.Model,
 		event.EventID,
 	)
+	if event.EventID == "" {
+		data += fmt.Sprintf(":%s:%d", source, line)
+	}
 	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:8])
+	return hex.EncodeToString(hash[:])
 }
 
 // identifySessionBlocks groups entries into 5-hour session blocks
@@ -547,7 +1455,11 @@ func floorToHour(t time.Time) time.Time {
 func createBlock(startTime time.Time, entries []UsageEvent, now time.Time, sessionDuration time.Duration) SessionBlock {
 	endTime := startTime.Add(sessionDuration)
 	actualEndTime := entries[len(entries)-1].Timestamp
-	isActive := now.Sub(actualEndTime) < sessionDuration && now.Before(endTime)
+	threshold := sessionDuration
+	if ActiveThreshold > 0 {
+		threshold = ActiveThreshold
+	}
+	isActive := now.Sub(actualEndTime) < threshold && now.Before(endTime)
 
 	block := SessionBlock{
 		ID:            startTime.Format(time.RFC3339),
@@ -593,182 +1505,201 @@ func createGapBlock(lastActivity, nextActivity time.Time, sessionDuration time.D
 
 // LoadDailyUsage loads and aggregates usage by day and model across all projects
 func LoadDailyUsage() ([]DailyUsage, error) {
-	projects, err := ListProjects()
-	if err != nil {
+	allEvents, err := LoadAllEvents()
+	if err != nil && !IsPartial(err) {
 		return nil, err
 	}
 
-	var allEvents []UsageEvent
-	dedupeCache := make(map[string]bool)
-
-	for _, project := range projects {
-		events, err := parseProjectEventsWithDedupe(project.Path, dedupeCache)
-		if err != nil {
-			continue
-		}
-		allEvents = append(allEvents, events...)
-	}
-
-	sort.Slice(allEvents, func(i, j int) bool {
-		return allEvents[i].Timestamp.Before(allEvents[j].Timestamp)
-	})
-
-	return aggregateByDay(allEvents), nil
+	return aggregateByDay(allEvents), err
 }
 
-// LoadDailyUsageForProject loads daily usage for a specific project path
-func LoadDailyUsageForProject(projectPath string) ([]DailyUsage, error) {
-	dedupeCache := make(map[string]bool)
-	events, err := parseProjectEventsWithDedupe(projectPath, dedupeCache)
-	if err != nil {
-		return nil, err
-	}
-
-	sort.Slice(events, func(i, j int) bool {
-		return events[i].Timestamp.Before(events[j].Timestamp)
-	})
+// DailyUsageForEvents aggregates already-loaded events by day and model
+func DailyUsageForEvents(events []UsageEvent) []DailyUsage {
+	return aggregateByDay(events)
+}
 
-	return aggregateByDay(events), nil
+// LoadDailyUsageForProject loads daily usage for a specific project
+func LoadDailyUsageForProject(project Project) ([]DailyUsage, error) {
+	events, err := parseProjectEvents(project)
+	return aggregateByDay(events), err
 }
 
 // LoadGroupedUsage loads usage grouped by the specified period (hour, day, week, month, year)
 func LoadGroupedUsage(groupBy string) ([]GroupedUsage, error) {
-	projects, err := ListProjects()
-	if err != nil {
+	allEvents, err := LoadAllEvents()
+	if err != nil && !IsPartial(err) {
 		return nil, err
 	}
 
-	var allEvents []UsageEvent
-	dedupeCache := make(map[string]bool)
-
-	for _, project := range projects {
-		events, err := parseProjectEventsWithDedupe(project.Path, dedupeCache)
-		if err != nil {
-			continue
-		}
-		allEvents = append(allEvents, events...)
-	}
-
-	sort.Slice(allEvents, func(i, j int) bool {
-		return allEvents[i].Timestamp.Before(allEvents[j].Timestamp)
-	})
-
-	return aggregateByPeriod(allEvents, groupBy), nil
+	return LoadGroupedUsageForEvents(allEvents, groupBy), err
 }
 
 // LoadGroupedUsageForProject loads grouped usage for a specific project
-func LoadGroupedUsageForProject(projectPath, groupBy string) ([]GroupedUsage, error) {
-	dedupeCache := make(map[string]bool)
-	events, err := parseProjectEventsWithDedupe(projectPath, dedupeCache)
-	if err != nil {
-		return nil, err
-	}
-
-	sort.Slice(events, func(i, j int) bool {
-		return events[i].Timestamp.Before(events[j].Timestamp)
-	})
-
-	return aggregateByPeriod(events, groupBy), nil
+func LoadGroupedUsageForProject(project Project, groupBy string) ([]GroupedUsage, error) {
+	events, err := parseProjectEvents(project)
+	return LoadGroupedUsageForEvents(events, groupBy), err
 }
 
 // LoadGroupedUsageForEvents aggregates usage for a specific set of events
 func LoadGroupedUsageForEvents(events []UsageEvent, groupBy string) []GroupedUsage {
-	if groupBy == "project" {
+	switch groupBy {
+	case "project":
 		return aggregateByProject(events)
+	case "session":
+		return aggregateBySession(events)
+	case "role":
+		return aggregateByRole(events)
 	}
 	return aggregateByPeriod(events, groupBy)
 }
 
+// aggregateByRole groups events by message role, in name order
+func aggregateByRole(events []UsageEvent) []GroupedUsage {
+	result := aggregateByKey(events, func(e UsageEvent) string {
+		return e.Role
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Period < result[j].Period
+	})
+	return result
+}
+
 func aggregateByProject(events []UsageEvent) []GroupedUsage {
-	projectMap := make(map[string]*GroupedUsage)
-	var projects []string
+	result := aggregateByKey(events, func(e UsageEvent) string {
+		return e.Project
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Period < result[j].Period
+	})
+	return result
+}
+
+// aggregateBySession groups events by their session ID, in order of first
+// activity. Events without one are grouped by the 5-hour block they fall in.
+func aggregateBySession(events []UsageEvent) []GroupedUsage {
+	events = withSessionIDs(events)
+	result := aggregateByKey(events, func(e UsageEvent) string {
+		return e.SessionID
+	})
+	order := sessionOrder(events)
+	slices.SortStableFunc(result, func(a, b GroupedUsage) int {
+		return order(a.Period, b.Period)
+	})
+	return result
+}
 
+// sessionOrder returns the comparator that puts session IDs in order of
+// first activity, breaking ties by ID, so it doesn't depend on the order
+// events arrive in
+func sessionOrder(events []UsageEvent) func(a, b string) int {
+	first := make(map[string]time.Time)
 	for _, e := range events {
-		project := e.Project
-		if project == "" {
-			project = "unknown"
+		id := e.SessionID
+		if id == "" {
+			id = "unknown"
 		}
-
-		if _, ok := projectMap[project]; !ok {
-			projectMap[project] = &GroupedUsage{
-				Period:  project,
-				ByModel: make(map[string]*ModelUsage),
-			}
-			projects = append(projects, project)
+		if t, ok := first[id]; !ok || e.Timestamp.Before(t) {
+			first[id] = e.Timestamp
 		}
+	}
+	return func(a, b string) int {
+		if c := first[a].Compare(first[b]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	}
+}
 
-		p := projectMap[project]
-		p.InputTotal += e.InputTokens
-		p.OutputTotal += e.OutputTokens
-		p.CacheCreateTotal += e.CacheCreation
-		p.CacheReadTotal += e.CacheRead
-
-		model := shortModelName(e.Model)
-		if model == "" {
-			model = "unknown"
+// withSessionIDs returns a copy of events with missing session IDs filled
+// in from the ID of the session block containing the event
+func withSessionIDs(events []UsageEvent) []UsageEvent {
+	var missing []UsageEvent
+	for _, e := range events {
+		if e.SessionID == "" {
+			missing = append(missing, e)
 		}
+	}
+	if len(missing) == 0 {
+		return events
+	}
 
-		if _, ok := p.ByModel[model]; !ok {
-			p.ByModel[model] = &ModelUsage{Model: model}
+	blockIDs := make(map[time.Time]string)
+	for _, block := range identifySessionBlocks(missing, DefaultSessionDuration) {
+		for _, e := range block.Entries {
+			blockIDs[e.Timestamp] = block.ID
 		}
-		p.ByModel[model].Input += e.InputTokens
-		p.ByModel[model].Output += e.OutputTokens
-		p.ByModel[model].CacheCreate += e.CacheCreation
-		p.ByModel[model].CacheRead += e.CacheRead
 	}
 
-	var result []GroupedUsage
-	sort.Strings(projects)
-	for _, project := range projects {
-		p := projectMap[project]
-		for m := range p.ByModel {
-			p.Models = append(p.Models, m)
+	filled := make([]UsageEvent, len(events))
+	for i, e := range events {
+		if e.SessionID == "" {
+			e.SessionID = blockIDs[e.Timestamp]
 		}
-		sort.Strings(p.Models)
-		result = append(result, *p)
+		filled[i] = e
 	}
+	return filled
+}
 
+// aggregateByPeriod groups events by time period, in the period order of
+// the grouping whatever order the events are in
+func aggregateByPeriod(events []UsageEvent, groupBy string) []GroupedUsage {
+	result := aggregateByKey(events, func(e UsageEvent) string {
+		return formatPeriod(e.Timestamp.Local(), groupBy)
+	})
+	order := periodOrder(groupBy)
+	slices.SortStableFunc(result, func(a, b GroupedUsage) int {
+		return order(a.Period, b.Period)
+	})
 	return result
 }
 
-func aggregateByPeriod(events []UsageEvent, groupBy string) []GroupedUsage {
-	periodMap := make(map[string]*GroupedUsage)
-	var periods []string
+// aggregateByKey groups events by the given key, keeping the order in which
+// keys are first seen. Empty keys are grouped as "unknown".
+func aggregateByKey(events []UsageEvent, keyFn func(UsageEvent) string) []GroupedUsage {
+	groupMap := make(map[string]*GroupedUsage)
+	var keys []string
 
 	for _, e := range events {
-		period := formatPeriod(e.Timestamp.Local(), groupBy)
+		key := keyFn(e)
+		if key == "" {
+			key = "unknown"
+		}
 
-		if _, ok := periodMap[period]; !ok {
-			periodMap[period] = &GroupedUsage{
-				Period:  period,
+		if _, ok := groupMap[key]; !ok {
+			groupMap[key] = &GroupedUsage{
+				Period:  key,
 				ByModel: make(map[string]*ModelUsage),
 			}
-			periods = append(periods, period)
+			keys = append(keys, key)
 		}
 
-		p := periodMap[period]
+		p := groupMap[key]
 		p.InputTotal += e.InputTokens
 		p.OutputTotal += e.OutputTokens
 		p.CacheCreateTotal += e.CacheCreation
+		p.CacheCreate1h += e.CacheCreation1h
 		p.CacheReadTotal += e.CacheRead
-
-		model := shortModelName(e.Model)
-		if model == "" {
-			model = "unknown"
+		p.Requests++
+		if e.TotalTokens() == 0 {
+			p.ZeroEvents++
 		}
 
+		model := displayModelName(e.Model)
+
 		if _, ok := p.ByModel[model]; !ok {
 			p.ByModel[model] = &ModelUsage{Model: model}
 		}
 		p.ByModel[model].Input += e.InputTokens
 		p.ByModel[model].Output += e.OutputTokens
 		p.ByModel[model].CacheCreate += e.CacheCreation
+		p.ByModel[model].CacheCreate1h += e.CacheCreation1h
 		p.ByModel[model].CacheRead += e.CacheRead
+		p.ByModel[model].Requests++
 	}
 
 	var result []GroupedUsage
-	for _, period := range periods {
-		p := periodMap[period]
+	for _, key := range keys {
+		p := groupMap[key]
 		for m := range p.ByModel {
 			p.Models = append(p.Models, m)
 		}
@@ -779,85 +1710,219 @@ func aggregateByPeriod(events []UsageEvent, groupBy string) []GroupedUsage {
 	return result
 }
 
+// weekdays are the --group weekday periods, in the order they're shown
+var weekdays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// periodOrder returns the comparator that puts periods of a time grouping
+// in order, so it doesn't depend on the order events arrive in. Date and
+// time labels are zero padded, most significant part first, so they sort
+// as strings; weekdays run Monday to Sunday.
+func periodOrder(groupBy string) func(a, b string) int {
+	switch groupBy {
+	case "weekday":
+		return compareWeekdays
+	default:
+		return strings.Compare
+	}
+}
+
+func compareWeekdays(a, b string) int {
+	return slices.Index(weekdays, a) - slices.Index(weekdays, b)
+}
+
+// comparePeriods orders period labels whose grouping isn't known, such as
+// those read back from a snapshot
+func comparePeriods(a, b string) int {
+	if slices.Contains(weekdays, a) && slices.Contains(weekdays, b) {
+		return compareWeekdays(a, b)
+	}
+	return strings.Compare(a, b)
+}
+
 func formatPeriod(t time.Time, groupBy string) string {
+	minute, hour := "2006-01-02 15:04", "2006-01-02 15:00"
+	if UTC {
+		minute, hour = "2006-01-02T15:04Z", "2006-01-02T15:00Z"
+	}
+	if d, ok := BucketDuration(groupBy); ok {
+		return bucketStart(t, d).Format(minute)
+	}
+
 	switch groupBy {
+	case "minute":
+		return t.Format(minute)
 	case "hour":
-		return t.Format("2006-01-02 15:00")
+		return t.Format(hour)
 	case "week":
 		year, week := t.ISOWeek()
 		return fmt.Sprintf("%d-W%02d", year, week)
 	case "month":
 		return t.Format("2006-01")
+	case "quarter":
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())+2)/3)
+	case "weekday":
+		return t.Weekday().String()
+	case "all":
+		return AllPeriod
 	case "year":
 		return t.Format("2006")
 	default: // day
-		return t.Format("Jan 02")
+		return t.Format("2006-01-02")
 	}
 }
 
+// AllPeriod labels the single period of --group all, which holds every event
+const AllPeriod = "All Time"
+
+// BucketDuration parses a --group value such as "15m" as a fixed-length
+// bucket. Buckets run from one minute to one day.
+func BucketDuration(groupBy string) (time.Duration, bool) {
+	d, err := time.ParseDuration(groupBy)
+	if err != nil || d < time.Minute || d > 24*time.Hour {
+		return 0, false
+	}
+	return d, true
+}
+
+// bucketStart truncates t to a multiple of d counted from local midnight,
+// so buckets line up with the wall clock in any time zone
+func bucketStart(t time.Time, d time.Duration) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return midnight.Add(t.Sub(midnight) / d * d)
+}
+
 func aggregateByDay(events []UsageEvent) []DailyUsage {
-	dayMap := make(map[string]*DailyUsage)
-	var days []string
+	var result []DailyUsage
+	for _, g := range aggregateByPeriod(events, "day") {
+		result = append(result, DailyUsage{
+			Date:             g.Period,
+			Models:           g.Models,
+			InputTotal:       g.InputTotal,
+			OutputTotal:      g.OutputTotal,
+			CacheCreateTotal: g.CacheCreateTotal,
+			CacheCreate1h:    g.CacheCreate1h,
+			CacheReadTotal:   g.CacheReadTotal,
+			ZeroEvents:       g.ZeroEvents,
+			Requests:         g.Requests,
+			ByModel:          g.ByModel,
+		})
+	}
+	return result
+}
 
-	for _, e := range events {
-		date := e.Timestamp.Local().Format("2006-01-02")
+// KeepZero keeps usage records whose token counts are all zero, which are
+// otherwise dropped, so they can be inspected when reconciling counts
+var KeepZero bool
 
-		if _, ok := dayMap[date]; !ok {
-			dayMap[date] = &DailyUsage{
-				Date:    date,
-				ByModel: make(map[string]*ModelUsage),
-			}
-			days = append(days, date)
-		}
+// UTC labels periods in UTC, with times of day in ISO 8601 such as
+// "2025-03-01T09:00Z", and moves event timestamps to UTC. The caller also
+// sets time.Local to UTC so periods are bucketed in it.
+var UTC bool
 
-		day := dayMap[date]
-		day.InputTotal += e.InputTokens
-		day.OutputTotal += e.OutputTokens
-		day.CacheCreateTotal += e.CacheCreation
-		day.CacheReadTotal += e.CacheRead
+// CarryModel gives usage records without a model the last model seen
+// earlier in the same log, such as streamed continuations that omit it.
+// It's a guess, so off by default.
+var CarryModel bool
 
-		model := shortModelName(e.Model)
-		if model == "" {
-			model = "unknown"
-		}
+// RawModels keeps model identifiers as logged, e.g. "claude-opus-4-20250514",
+// instead of normalizing them in aggregated usage
+var RawModels bool
+
+// displayModelName is the name usage is aggregated under: the normalized
+// name, or the raw identifier with RawModels, and "unknown" when missing
+func displayModelName(model string) string {
+	name := model
+	if !RawModels {
+		name = shortModelName(model)
+	}
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
+
+// ModelName is the name a model's usage is reported under, honoring
+// RawModels
+func ModelName(model string) string {
+	return displayModelName(model)
+}
+
+// shortModelName normalizes a model identifier to its family and version,
+// e.g. "claude-opus-4-20250514" to "opus-4" and "claude-3-5-sonnet-20241022"
+// to "sonnet-3-5". Unrecognized models are returned unchanged.
+func shortModelName(model string) string {
+	tokens := strings.FieldsFunc(strings.ToLower(model), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
 
-		if _, ok := day.ByModel[model]; !ok {
-			day.ByModel[model] = &ModelUsage{Model: model}
+	isVersion := func(t string) bool {
+		if len(t) == 0 || len(t) > 2 {
+			return false // Dates and other long numbers aren't versions
 		}
-		day.ByModel[model].Input += e.InputTokens
-		day.ByModel[model].Output += e.OutputTokens
-		day.ByModel[model].CacheCreate += e.CacheCreation
-		day.ByModel[model].CacheRead += e.CacheRead
+		_, err := strconv.Atoi(t)
+		return err == nil
 	}
 
-	// Build result with sorted models
-	var result []DailyUsage
-	for _, date := range days {
-		day := dayMap[date]
+	for i, t := range tokens {
+		if t != "opus" && t != "sonnet" && t != "haiku" {
+			continue
+		}
+
+		// Newer names put the version after the family (claude-opus-4-5)
+		var version []string
+		for _, v := range tokens[i+1:] {
+			if !isVersion(v) {
+				break
+			}
+			version = append(version, v)
+		}
 
-		// Collect and sort model names
-		for m := range day.ByModel {
-			day.Models = append(day.Models, m)
+		// Older names put it before (claude-3-5-sonnet)
+		if len(version) == 0 {
+			start := i
+			for start > 0 && isVersion(tokens[start-1]) {
+				start--
+			}
+			version = tokens[start:i]
 		}
-		sort.Strings(day.Models)
 
-		result = append(result, *day)
+		if len(version) == 0 {
+			return t
+		}
+		return t + "-" + strings.Join(version, "-")
 	}
 
-	return result
+	return model
 }
 
-func shortModelName(model string) string {
-	if strings.Contains(model, "opus") {
-		return "opus-4-5"
-	}
-	if strings.Contains(model, "sonnet") {
-		return "sonnet-4-5"
-	}
-	if strings.Contains(model, "haiku") {
-		return "haiku-4-5"
+// TokenCounts holds token totals by type
+type TokenCounts struct {
+	Input         int
+	Output        int
+	CacheCreation int
+	CacheRead     int
+}
+
+// TotalTokens returns sum of all token types
+func (t TokenCounts) TotalTokens() int {
+	return t.Input + t.Output + t.CacheCreation + t.CacheRead
+}
+
+// RollingUsage sums the events in the window ending at now, ignoring
+// session block boundaries
+func RollingUsage(events []UsageEvent, window time.Duration, now time.Time) TokenCounts {
+	var counts TokenCounts
+	start := now.Add(-window)
+	for _, e := range events {
+		if !e.Timestamp.After(start) || e.Timestamp.After(now) {
+			continue
+		}
+		counts.Input += e.InputTokens
+		counts.Output += e.OutputTokens
+		counts.CacheCreation += e.CacheCreation
+		counts.CacheRead += e.CacheRead
 	}
-	return model
+	return counts
 }
 
 // CalculateBurnRate calculates tokens/minute and cost/hour for a block
@@ -922,10 +1987,20 @@ func getString(m map[string]interface{}, key string) string {
 	return ""
 }
 
-// FormatTokens formats token counts with commas
+// FormatTokens formats token counts with ThousandsSep between digit groups
 func FormatTokens(n int) string {
+	return FormatTokensSep(n, ThousandsSep)
+}
+
+// ThousandsSep separates digit groups in FormatTokens, e.g. "." or " " for
+// locales that don't use a comma
+var ThousandsSep = ","
+
+// FormatTokensSep formats a token count with sep between groups of three
+// digits
+func FormatTokensSep(n int, sep string) string {
 	if n < 0 {
-		return "-" + FormatTokens(-n)
+		return "-" + FormatTokensSep(-n, sep)
 	}
 
 	s := strconv.Itoa(n)
@@ -938,20 +2013,47 @@ func FormatTokens(n int) string {
 	if remainder > 0 {
 		result.WriteString(s[:remainder])
 		if len(s) > remainder {
-			result.WriteString(",")
+			result.WriteString(sep)
 		}
 	}
 
 	for i := remainder; i < len(s); i += 3 {
 		result.WriteString(s[i : i+3])
 		if i+3 < len(s) {
-			result.WriteString(",")
+			result.WriteString(sep)
 		}
 	}
 
 	return result.String()
 }
 
+// FormatTokensIn formats a token count in a fixed unit, "k" for thousands
+// or "m" for millions, so a column of counts lines up. Any other unit
+// formats the full count.
+func FormatTokensIn(n int, unit string) string {
+	if n < 0 {
+		return "-" + FormatTokensIn(-n, unit)
+	}
+
+	switch unit {
+	case "k":
+		return formatScaled(n, 1_000, 1) + "K"
+	case "m":
+		return formatScaled(n, 1_000_000, 2) + "M"
+	default:
+		return FormatTokens(n)
+	}
+}
+
+// formatScaled divides n by scale, rounding to the given decimals and
+// grouping the whole part's digits like FormatTokens
+func formatScaled(n, scale, decimals int) string {
+	s := strconv.FormatFloat(float64(n)/float64(scale), 'f', decimals, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	w, _ := strconv.Atoi(whole)
+	return FormatTokens(w) + "." + frac
+}
+
 // FormatTokensShort formats token counts with K/M/B suffixes
 func FormatTokensShort(n int) string {
 	if n < 0 {
@@ -993,3 +2095,31 @@ func FormatDuration(d time.Duration) string {
 	}
 	return fmt.Sprintf("%dm", minutes)
 }
+
+// FormatRelative formats t relative to now, e.g. "2 hours ago"
+func FormatRelative(t, now time.Time) string {
+	d := now.Sub(t)
+	if d < time.Minute {
+		return "just now"
+	}
+
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+
+	switch {
+	case d < time.Hour:
+		return plural(int(d.Minutes()), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d.Hours()), "hour")
+	case d < 30*24*time.Hour:
+		return plural(int(d.Hours()/24), "day")
+	case d < 365*24*time.Hour:
+		return plural(int(d.Hours()/24/30), "month")
+	default:
+		return plural(int(d.Hours()/24/365), "year")
+	}
+}
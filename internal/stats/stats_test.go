@@ -0,0 +1,1671 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAggregateByPeriodSeparatesYears(t *testing.T) {
+	events := []UsageEvent{
+		{Timestamp: time.Date(2024, 1, 2, 10, 0, 0, 0, time.Local), InputTokens: 100, Model: "claude-sonnet-4-5"},
+		{Timestamp: time.Date(2025, 1, 2, 10, 0, 0, 0, time.Local), InputTokens: 200, Model: "claude-sonnet-4-5"},
+	}
+
+	tests := []struct {
+		groupBy string
+		want    []string
+	}{
+		{"minute", []string{"2024-01-02 10:00", "2025-01-02 10:00"}},
+		{"hour", []string{"2024-01-02 10:00", "2025-01-02 10:00"}},
+		{"day", []string{"2024-01-02", "2025-01-02"}},
+		{"week", []string{"2024-W01", "2025-W01"}},
+		{"month", []string{"2024-01", "2025-01"}},
+		{"quarter", []string{"2024-Q1", "2025-Q1"}},
+		{"year", []string{"2024", "2025"}},
+	}
+
+	for _, tt := range tests {
+		usage := aggregateByPeriod(events, tt.groupBy)
+		if len(usage) != len(tt.want) {
+			t.Fatalf("%s: got %d periods, want %d", tt.groupBy, len(usage), len(tt.want))
+		}
+		for i, u := range usage {
+			if u.Period != tt.want[i] {
+				t.Errorf("%s: period %d = %q, want %q", tt.groupBy, i, u.Period, tt.want[i])
+			}
+			if u.InputTotal != events[i].InputTokens {
+				t.Errorf("%s: period %q input = %d, want %d", tt.groupBy, u.Period, u.InputTotal, events[i].InputTokens)
+			}
+		}
+	}
+}
+
+func TestAggregateByPeriodOrdersUnsortedEvents(t *testing.T) {
+	// A Sunday in December, then the Monday before it and a January day
+	sun := time.Date(2024, 12, 29, 9, 0, 0, 0, time.Local)
+	events := []UsageEvent{
+		{Timestamp: sun, InputTokens: 1},
+		{Timestamp: sun.AddDate(0, 0, 4), InputTokens: 1},
+		{Timestamp: sun.AddDate(0, 0, -6), InputTokens: 1},
+	}
+
+	tests := []struct {
+		groupBy string
+		want    []string
+	}{
+		{"day", []string{"2024-12-23", "2024-12-29", "2025-01-02"}},
+		{"month", []string{"2024-12", "2025-01"}},
+		{"quarter", []string{"2024-Q4", "2025-Q1"}},
+		{"weekday", []string{"Monday", "Thursday", "Sunday"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, u := range aggregateByPeriod(events, tt.groupBy) {
+			got = append(got, u.Period)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.groupBy, got, tt.want)
+		}
+
+		var keys []string
+		for _, n := range AggregateTree(events, []string{tt.groupBy}) {
+			keys = append(keys, n.Key)
+		}
+		if !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("%s tree: got %v, want %v", tt.groupBy, keys, tt.want)
+		}
+	}
+}
+
+func TestWeekdayPeriods(t *testing.T) {
+	// A Wednesday, then the following Sunday and Monday
+	wed := time.Date(2025, 3, 5, 9, 0, 0, 0, time.Local)
+	events := []UsageEvent{
+		{Timestamp: wed, InputTokens: 1},
+		{Timestamp: wed.AddDate(0, 0, 4), InputTokens: 2},
+		{Timestamp: wed.AddDate(0, 0, 5), InputTokens: 4},
+		{Timestamp: wed.AddDate(0, 0, 7), InputTokens: 8},
+	}
+
+	var got []string
+	for _, u := range aggregateByPeriod(events, "weekday") {
+		got = append(got, fmt.Sprintf("%s=%d", u.Period, u.InputTotal))
+	}
+	want := []string{"Monday=4", "Wednesday=9", "Sunday=2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var costs []string
+	for _, c := range DefaultPricing().PeriodCosts(events, "weekday") {
+		costs = append(costs, c.Period)
+	}
+	if want := []string{"Monday", "Wednesday", "Sunday"}; !reflect.DeepEqual(costs, want) {
+		t.Errorf("cost periods = %v, want %v", costs, want)
+	}
+}
+
+func TestAllPeriod(t *testing.T) {
+	// Years apart, so no other grouping would put them together
+	events := []UsageEvent{
+		{Timestamp: time.Date(2024, 6, 1, 9, 0, 0, 0, time.Local), InputTokens: 1, Model: "claude-opus-4-20250514"},
+		{Timestamp: time.Date(2025, 3, 5, 9, 0, 0, 0, time.Local), InputTokens: 2, Model: "claude-sonnet-4-20250514"},
+	}
+
+	usage := aggregateByPeriod(events, "all")
+	if len(usage) != 1 {
+		t.Fatalf("got %d periods, want 1", len(usage))
+	}
+	if u := usage[0]; u.Period != AllPeriod || u.InputTotal != 3 || u.Requests != 2 || len(u.Models) != 2 {
+		t.Errorf("got %+v, want one %q period of both events", u, AllPeriod)
+	}
+}
+
+func TestRequestCounts(t *testing.T) {
+	day := time.Date(2025, 3, 1, 9, 0, 0, 0, time.Local)
+	events := []UsageEvent{
+		{Timestamp: day, Model: "claude-sonnet-4", InputTokens: 10},
+		{Timestamp: day.Add(time.Minute), Model: "claude-sonnet-4", OutputTokens: 5},
+		{Timestamp: day.Add(2 * time.Minute), Model: "claude-opus-4", InputTokens: 1},
+		{Timestamp: day.AddDate(0, 0, 1), Model: "claude-opus-4", InputTokens: 1},
+	}
+
+	usage := aggregateByPeriod(events, "day")
+	if len(usage) != 2 {
+		t.Fatalf("got %d periods, want 2", len(usage))
+	}
+	if usage[0].Requests != 3 || usage[1].Requests != 1 {
+		t.Errorf("requests = %d, %d, want 3, 1", usage[0].Requests, usage[1].Requests)
+	}
+	if got := usage[0].ByModel["sonnet-4"].Requests; got != 2 {
+		t.Errorf("sonnet-4 requests = %d, want 2", got)
+	}
+	if got := SumUsage(usage); got.Requests != 4 || got.ByModel["opus-4"].Requests != 2 {
+		t.Errorf("summed requests = %d, opus-4 %d, want 4, 2", got.Requests, got.ByModel["opus-4"].Requests)
+	}
+}
+
+func TestQuarterPeriods(t *testing.T) {
+	tests := []struct {
+		month time.Month
+		want  string
+	}{
+		{time.January, "2025-Q1"},
+		{time.March, "2025-Q1"},
+		{time.April, "2025-Q2"},
+		{time.September, "2025-Q3"},
+		{time.October, "2025-Q4"},
+		{time.December, "2025-Q4"},
+	}
+	for _, tt := range tests {
+		if got := formatPeriod(time.Date(2025, tt.month, 15, 12, 0, 0, 0, time.Local), "quarter"); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.month, got, tt.want)
+		}
+	}
+
+	// Q4 of one year comes before Q1 of the next
+	events := []UsageEvent{
+		{Timestamp: time.Date(2024, 12, 31, 12, 0, 0, 0, time.Local), InputTokens: 1},
+		{Timestamp: time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local), InputTokens: 2},
+	}
+	usage := aggregateByPeriod(events, "quarter")
+	if len(usage) != 2 || usage[0].Period != "2024-Q4" || usage[1].Period != "2025-Q1" {
+		t.Errorf("got %+v, want 2024-Q4 then 2025-Q1", usage)
+	}
+}
+
+func TestParseJSONLKeepsDistinctEventsWithoutID(t *testing.T) {
+	input := strings.Join([]string{
+		`{"timestamp":"2025-01-02T10:00:00.000Z","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":10,"output_tokens":5}}}`,
+		`{"timestamp":"2025-01-02T10:00:00.000Z","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":10,"output_tokens":5}}}`,
+		`{"timestamp":"2025-01-02T10:00:00.000Z","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":10,"output_tokens":5}}}`,
+		`{"timestamp":"2025-01-02T10:00:00.000Z","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":10,"output_tokens":5}}}`,
+	}, "\n")
+
+	events := parseJSONL(strings.NewReader(input), "test.jsonl", make(map[string]bool), "test")
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3 (two without ID, one deduplicated with ID)", len(events))
+	}
+}
+
+func TestParseJSONLSkipsMalformedLines(t *testing.T) {
+	input := strings.Join([]string{
+		`{"timestamp":"2025-01-02T10:00:00Z","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+		`{"timestamp":"2025-01-02T10:01:00Z","message":{"id":"msg_2","usa`,
+		`{"timestamp":"2025-01-02T10:02:00Z","message":{"id":"msg_3","usage":{"input_tokens":30}}}`,
+		`{"timestamp":"2025-01-02T10:03:00Z","message":{"id":"msg_4","usage":{"input_tokens":40}}}`,
+		`{"timestamp":"2025-01-02T10:04:00Z","message":{"id":"msg_5","us`,
+	}, "\n")
+
+	before := SkippedTotals().Partial
+	events := parseJSONL(strings.NewReader(input), "malformed.jsonl", make(map[string]bool), "test")
+
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, e.EventID)
+	}
+	if got, want := strings.Join(ids, ","), "msg_1,msg_3,msg_4"; got != want {
+		t.Errorf("got events %s, want %s", got, want)
+	}
+	// Only the unfinished final line counts as partial
+	if got := SkippedTotals().Partial - before; got != 1 {
+		t.Errorf("counted %d partial lines, want 1", got)
+	}
+}
+
+func TestExtractCacheCreation(t *testing.T) {
+	tests := []struct {
+		usage          string
+		total, oneHour int
+	}{
+		{`{"cache_creation_input_tokens":100}`, 100, 0},
+		{`{"cache_creation_input_tokens":100,"cache_creation":{"ephemeral_5m_input_tokens":60,"ephemeral_1h_input_tokens":40}}`, 100, 40},
+		{`{"cache_creation_input_tokens_5m":60,"cache_creation_input_tokens_1h":40}`, 100, 40},
+	}
+	for _, tt := range tests {
+		var usage map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.usage), &usage); err != nil {
+			t.Fatal(err)
+		}
+		total, oneHour := extractCacheCreation(usage)
+		if total != tt.total || oneHour != tt.oneHour {
+			t.Errorf("%s: got %d/%d, want %d/%d", tt.usage, total, oneHour, tt.total, tt.oneHour)
+		}
+	}
+}
+
+func TestAggregateTreeOrdering(t *testing.T) {
+	events := []UsageEvent{
+		{Timestamp: time.Date(2025, 1, 2, 10, 0, 0, 0, time.Local), InputTokens: 1, Project: "zeta", Model: "claude-opus-4-5"},
+		{Timestamp: time.Date(2025, 1, 2, 11, 0, 0, 0, time.Local), InputTokens: 2, Project: "alpha", Model: "claude-sonnet-4-5"},
+		{Timestamp: time.Date(2025, 1, 3, 10, 0, 0, 0, time.Local), InputTokens: 4, Project: "alpha", Model: "claude-opus-4-5"},
+	}
+
+	tree := AggregateTree(events, []string{"day", "project", "model"})
+	if len(tree) != 2 || tree[0].Key != "2025-01-02" || tree[1].Key != "2025-01-03" {
+		t.Fatalf("unexpected periods: %+v", tree)
+	}
+	projects := tree[0].Children
+	if len(projects) != 2 || projects[0].Key != "alpha" || projects[1].Key != "zeta" {
+		t.Fatalf("unexpected projects: %+v", projects)
+	}
+	if tree[0].Input != 3 || projects[0].Children[0].Key != "sonnet-4-5" {
+		t.Errorf("unexpected totals or models: %+v", projects[0])
+	}
+	if tree[0].Requests != 2 || projects[0].Requests != 1 || projects[0].Children[0].Requests != 1 {
+		t.Errorf("got %d, %d and %d requests, want 2, 1 and 1", tree[0].Requests, projects[0].Requests, projects[0].Children[0].Requests)
+	}
+}
+
+func TestAggregateTreeOrdersRolesAndSessions(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2025, 1, 2, hour, 0, 0, 0, time.UTC) }
+	// Out of order, and with two sessions starting together
+	events := []UsageEvent{
+		{Timestamp: at(12), InputTokens: 1, SessionID: "late", Role: "user"},
+		{Timestamp: at(10), InputTokens: 2, SessionID: "b", Role: "assistant"},
+		{Timestamp: at(10), InputTokens: 4, SessionID: "a", Role: "user"},
+	}
+
+	keys := func(nodes []*UsageNode) []string {
+		var keys []string
+		for _, n := range nodes {
+			keys = append(keys, n.Key)
+		}
+		return keys
+	}
+	if got, want := keys(AggregateTree(events, []string{"role"})), []string{"assistant", "user"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got roles %v, want %v", got, want)
+	}
+	if got, want := keys(AggregateTree(events, []string{"session"})), []string{"a", "b", "late"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got sessions %v, want %v", got, want)
+	}
+
+	var flat []string
+	for _, u := range aggregateBySession(events) {
+		flat = append(flat, u.Period)
+	}
+	if want := []string{"a", "b", "late"}; !reflect.DeepEqual(flat, want) {
+		t.Errorf("got flat sessions %v, want %v", flat, want)
+	}
+}
+
+func TestShortModelName(t *testing.T) {
+	tests := map[string]string{
+		"claude-opus-4-5-20251101":                  "opus-4-5",
+		"claude-opus-4-1-20250805":                  "opus-4-1",
+		"claude-opus-4-20250514":                    "opus-4",
+		"claude-sonnet-4-5-20250929":                "sonnet-4-5",
+		"claude-sonnet-4-20250514":                  "sonnet-4",
+		"claude-haiku-4-5-20251001":                 "haiku-4-5",
+		"claude-3-7-sonnet-20250219":                "sonnet-3-7",
+		"claude-3-5-sonnet-20241022":                "sonnet-3-5",
+		"claude-3-5-haiku-20241022":                 "haiku-3-5",
+		"claude-3-opus-20240229":                    "opus-3",
+		"claude-sonnet-4@20250514":                  "sonnet-4",
+		"anthropic.claude-3-5-sonnet-20241022-v2:0": "sonnet-3-5",
+		"opus":                                      "opus",
+		"<synthetic>":                               "<synthetic>",
+		"":                                          "",
+	}
+
+	for model, want := range tests {
+		if got := shortModelName(model); got != want {
+			t.Errorf("shortModelName(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestAggregateBySessionFallsBackToBlock(t *testing.T) {
+	start := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	events := []UsageEvent{
+		{Timestamp: start, InputTokens: 1, SessionID: "abc"},
+		{Timestamp: start.Add(time.Minute), InputTokens: 2},
+		{Timestamp: start.Add(2 * time.Minute), InputTokens: 4, SessionID: "abc"},
+	}
+
+	usage := aggregateBySession(events)
+	if len(usage) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(usage))
+	}
+	if usage[0].Period != "abc" || usage[0].InputTotal != 5 {
+		t.Errorf("session abc = %+v, want 5 input tokens", usage[0])
+	}
+	if want := start.Add(time.Minute).Format(time.RFC3339); usage[1].Period != want {
+		t.Errorf("fallback session = %q, want block ID %q", usage[1].Period, want)
+	}
+}
+
+func TestRollingUsageIgnoresBlockBoundaries(t *testing.T) {
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	events := []UsageEvent{
+		{Timestamp: now.Add(-6 * time.Hour), InputTokens: 1000},
+		{Timestamp: now.Add(-4 * time.Hour), InputTokens: 10, OutputTokens: 5},
+		{Timestamp: now.Add(-time.Minute), CacheRead: 100},
+		{Timestamp: now.Add(time.Minute), InputTokens: 1000},
+	}
+
+	got := RollingUsage(events, 5*time.Hour, now)
+	want := TokenCounts{Input: 10, Output: 5, CacheRead: 100}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestPeriodStats(t *testing.T) {
+	usage := []GroupedUsage{
+		{Period: "2025-01-01", InputTotal: 30},
+		{Period: "2025-01-02", InputTotal: 10},
+		{Period: "2025-01-03", InputTotal: 100},
+		{Period: "2025-01-04", InputTotal: 20},
+	}
+
+	got := PeriodStats(usage)
+	want := UsageStats{Max: 100, Min: 10, Avg: 40, Median: 25, MaxPeriod: "2025-01-03", MinPeriod: "2025-01-02"}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestStreaks(t *testing.T) {
+	var daily []DailyUsage
+	for _, date := range []string{
+		"2025-02-26", "2025-02-27", "2025-02-28", "2025-03-01", // Across a month end
+		"2025-03-05", "2025-03-06",
+	} {
+		daily = append(daily, DailyUsage{Date: date, InputTotal: 10})
+	}
+	daily = append(daily, DailyUsage{Date: "2025-03-07", ZeroEvents: 1})
+
+	tests := []struct {
+		now     string
+		current int
+	}{
+		{"2025-03-06", 2},
+		{"2025-03-07", 2}, // Today's first use is still to come
+		{"2025-03-08", 0},
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse("2006-01-02 15:04", tt.now+" 12:00")
+		current, longest := Streaks(daily, now)
+		if current != tt.current || longest != 4 {
+			t.Errorf("on %s got current %d, longest %d, want %d and 4", tt.now, current, longest, tt.current)
+		}
+	}
+}
+
+func TestLoadAllEventsKeepsReadableEventsOnError(t *testing.T) {
+	dir := t.TempDir()
+	project := filepath.Join(dir, "-home-user-proj")
+	if err := os.MkdirAll(project, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	line := `{"timestamp":"2025-01-02T10:00:00Z","message":{"id":"msg_1","usage":{"input_tokens":10}}}`
+	if err := os.WriteFile(filepath.Join(project, "a.jsonl"), []byte(line+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "missing"), filepath.Join(project, "broken.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+
+	Roots = []string{dir}
+	defer func() { Roots = nil }()
+
+	events, err := LoadAllEvents()
+	if !IsPartial(err) {
+		t.Fatalf("got error %v, want a partial error", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("got %d events, want 1", len(events))
+	}
+}
+
+func TestFormatTokensSep(t *testing.T) {
+	tests := []struct {
+		n    int
+		sep  string
+		want string
+	}{
+		{999, ".", "999"},
+		{1234567, ",", "1,234,567"},
+		{1234567, ".", "1.234.567"},
+		{-1234567, " ", "-1 234 567"},
+		{1234, "", "1234"},
+	}
+	for _, tt := range tests {
+		if got := FormatTokensSep(tt.n, tt.sep); got != tt.want {
+			t.Errorf("FormatTokensSep(%d, %q) = %q, want %q", tt.n, tt.sep, got, tt.want)
+		}
+	}
+}
+
+func TestFormatTokensIn(t *testing.T) {
+	tests := []struct {
+		n    int
+		unit string
+		want string
+	}{
+		{500, "k", "0.5K"},
+		{1_234_567, "k", "1,234.6K"},
+		{999_999, "k", "1,000.0K"},
+		{1_234_567, "m", "1.23M"},
+		{42, "m", "0.00M"},
+		{-1_500, "k", "-1.5K"},
+		{1_234_567, "raw", "1,234,567"},
+	}
+	for _, tt := range tests {
+		if got := FormatTokensIn(tt.n, tt.unit); got != tt.want {
+			t.Errorf("FormatTokensIn(%d, %q) = %q, want %q", tt.n, tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestPeriodCostsSplitsByTokenType(t *testing.T) {
+	p := Pricing{"sonnet": {Input: 3, Output: 15, CacheWrite: 4, CacheWrite1h: 6, CacheRead: 0.5}}
+	events := []UsageEvent{
+		{Timestamp: time.Date(2025, 1, 5, 12, 0, 0, 0, time.Local), Model: "claude-sonnet-4-5", InputTokens: 1_000_000, CacheCreation: 3_000_000, CacheCreation1h: 1_000_000},
+		{Timestamp: time.Date(2025, 2, 5, 12, 0, 0, 0, time.Local), Model: "claude-sonnet-4-5", OutputTokens: 1_000_000, CacheRead: 2_000_000},
+	}
+
+	got := p.PeriodCosts(events, "month")
+	want := []PeriodCost{
+		{Period: "2025-01", CostBreakdown: CostBreakdown{Input: 3, Cache: 14}},
+		{Period: "2025-02", CostBreakdown: CostBreakdown{Output: 15, Cache: 1}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d periods, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("period %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCacheSavings(t *testing.T) {
+	p := Pricing{
+		"sonnet": {Input: 3, CacheRead: 0.3},
+		"opus":   {Input: 15, CacheRead: 1.5},
+	}
+	u := GroupedUsage{ByModel: map[string]*ModelUsage{
+		"sonnet-4-5": {Model: "sonnet-4-5", Input: 500, CacheRead: 2_000_000},
+		"opus-4-1":   {Model: "opus-4-1", CacheRead: 1_000_000},
+		"mystery":    {Model: "mystery", CacheRead: 1_000_000},
+	}}
+
+	// 2M at $2.70 saved per million, 1M at $13.50, unpriced models skipped
+	if got, want := CacheSavings(u, p), 18.9; math.Abs(got-want) > 1e-9 {
+		t.Errorf("CacheSavings() = %v, want %v", got, want)
+	}
+}
+
+func TestParseJSONLFindsUsageInContent(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "content_usage.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	events := parseJSONL(f, "content_usage.jsonl", make(map[string]bool), "test")
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+
+	tests := []struct {
+		input, output, cacheCreate, cacheCreate1h, cacheRead int
+	}{
+		{100, 20, 0, 0, 0},
+		{50, 5, 10, 0, 0},
+		{100, 10, 40, 40, 500},
+	}
+	for i, tt := range tests {
+		e := events[i]
+		if e.InputTokens != tt.input || e.OutputTokens != tt.output || e.CacheCreation != tt.cacheCreate ||
+			e.CacheCreation1h != tt.cacheCreate1h || e.CacheRead != tt.cacheRead {
+			t.Errorf("event %d: got %+v, want %+v", i, e, tt)
+		}
+	}
+}
+
+func TestCarryModel(t *testing.T) {
+	log := strings.Join([]string{
+		`{"timestamp":"2025-01-02T10:00:00Z","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+		`{"timestamp":"2025-01-02T10:01:00Z","message":{"id":"msg_2","model":"claude-opus-4-20250514","usage":{"input_tokens":10}}}`,
+		`{"timestamp":"2025-01-02T10:02:00Z","message":{"id":"msg_3","usage":{"input_tokens":10}}}`,
+		`{"timestamp":"2025-01-02T10:03:00Z","message":{"id":"msg_4","model":"claude-sonnet-4-20250514","usage":{"input_tokens":10}}}`,
+		`{"timestamp":"2025-01-02T10:04:00Z","message":{"id":"msg_5","usage":{"input_tokens":10}}}`,
+	}, "\n")
+
+	tests := []struct {
+		carry bool
+		want  []string
+	}{
+		{false, []string{"", "claude-opus-4-20250514", "", "claude-sonnet-4-20250514", ""}},
+		// Nothing precedes the first record, so it stays unknown
+		{true, []string{"", "claude-opus-4-20250514", "claude-opus-4-20250514", "claude-sonnet-4-20250514", "claude-sonnet-4-20250514"}},
+	}
+	t.Cleanup(func() { CarryModel = false })
+	for _, tt := range tests {
+		CarryModel = tt.carry
+		events := parseJSONL(strings.NewReader(log), "test.jsonl", make(map[string]bool), "test")
+		var got []string
+		for _, e := range events {
+			got = append(got, e.Model)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("with CarryModel %v got models %q, want %q", tt.carry, got, tt.want)
+		}
+	}
+}
+
+func TestParseJSONLTakesFinalStreamingUsage(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "streaming_usage.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	events := parseJSONL(f, "streaming_usage.jsonl", make(map[string]bool), "test")
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+
+	tests := []struct {
+		input, output, cacheCreate, cacheRead int
+	}{
+		// Final output count, other counts only in the running usage
+		{100, 250, 20, 500},
+		// Final usage repeating every count
+		{40, 90, 0, 0},
+		// Content block shares summed, then topped up by the final usage
+		{100, 60, 0, 0},
+	}
+	for i, tt := range tests {
+		e := events[i]
+		if e.InputTokens != tt.input || e.OutputTokens != tt.output || e.CacheCreation != tt.cacheCreate ||
+			e.CacheRead != tt.cacheRead {
+			t.Errorf("event %d: got %+v, want %+v", i, e, tt)
+		}
+	}
+}
+
+func TestActiveThreshold(t *testing.T) {
+	now := time.Now()
+	entries := []UsageEvent{{Timestamp: now.Add(-45 * time.Minute), InputTokens: 10}}
+
+	if block := createBlock(now.Add(-time.Hour), entries, now, DefaultSessionDuration); !block.IsActive {
+		t.Error("block should be active by default")
+	}
+
+	ActiveThreshold = 30 * time.Minute
+	defer func() { ActiveThreshold = 0 }()
+	if block := createBlock(now.Add(-time.Hour), entries, now, DefaultSessionDuration); block.IsActive {
+		t.Error("block idle for 45m should be inactive with a 30m threshold")
+	}
+}
+
+func TestLoadPricingFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.json")
+	if err := os.WriteFile(path, []byte(`{"sonnet": {"input": 1}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pricing, err := LoadPricing(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pricing["sonnet"].Input; got != 1 {
+		t.Errorf("from the file got input rate %v, want 1", got)
+	}
+
+	// The environment takes precedence over the file
+	t.Setenv(PricingEnv, `{"sonnet": {"input": 2}}`)
+	pricing, err = LoadPricing(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pricing["sonnet"].Input; got != 2 {
+		t.Errorf("from %s got input rate %v, want 2", PricingEnv, got)
+	}
+	if _, ok := pricing["opus"]; !ok {
+		t.Error("defaults for other models were dropped")
+	}
+
+	t.Setenv(PricingEnv, `{"sonnet":`)
+	if _, err := LoadPricing(""); err == nil || !strings.Contains(err.Error(), PricingEnv) {
+		t.Errorf("got error %v, want one naming %s", err, PricingEnv)
+	}
+}
+
+func TestProjectMonthlyCost(t *testing.T) {
+	p := Pricing{"sonnet": {Input: 1}}
+	now := time.Date(2025, 4, 10, 12, 0, 0, 0, time.Local)
+	var events []UsageEvent
+	// $1 a day for the 7 days ending today, plus $5 last month
+	for i := 0; i < 7; i++ {
+		events = append(events, UsageEvent{Timestamp: now.AddDate(0, 0, -i), Model: "claude-sonnet-4", InputTokens: 1_000_000})
+	}
+	events = append(events, UsageEvent{Timestamp: time.Date(2025, 3, 31, 12, 0, 0, 0, time.Local), Model: "claude-sonnet-4", InputTokens: 5_000_000})
+	sort.Slice(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+	daily := DailyUsageForEvents(events)
+
+	if got := MonthToDateCost(daily, p, now); got != 7 {
+		t.Errorf("MonthToDateCost = %v, want 7", got)
+	}
+	if got := AverageDailyCost(daily, p, now); got != 1 {
+		t.Errorf("AverageDailyCost = %v, want 1", got)
+	}
+	// 20 days left in April at $1 a day
+	if got := ProjectMonthlyCost(daily, p, now); got != 27 {
+		t.Errorf("ProjectMonthlyCost = %v, want 27", got)
+	}
+
+	// The 10 days to April 10 run from April 1, the first three unused
+	costs := RecentDailyCosts(daily, p, now, 10)
+	if len(costs) != 10 || costs[0].Date != "2025-04-01" || costs[9].Date != "2025-04-10" {
+		t.Fatalf("RecentDailyCosts dates = %v", costs)
+	}
+	for i, c := range costs {
+		want := 1.0
+		if i < 3 {
+			want = 0
+		}
+		if c.Cost != want {
+			t.Errorf("%s cost = %v, want %v", c.Date, c.Cost, want)
+		}
+	}
+}
+
+// useFixtureRoots points project discovery at testdata/projects for the
+// duration of a test
+func useFixtureRoots(t *testing.T) {
+	t.Helper()
+	Roots = []string{filepath.Join("testdata", "projects")}
+	t.Cleanup(func() { Roots = nil })
+}
+
+func TestLoadProgress(t *testing.T) {
+	useFixtureRoots(t)
+
+	var updates []Progress
+	ProgressFunc = func(p Progress) { updates = append(updates, p) }
+	t.Cleanup(func() { ProgressFunc = nil })
+
+	events, err := LoadAllEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The total is known before the first file is parsed
+	if len(updates) != 3 || updates[0] != (Progress{FilesTotal: 2}) {
+		t.Fatalf("got progress %+v, want a start and one update per fixture log", updates)
+	}
+	if last := updates[len(updates)-1]; last.FilesDone != 2 || last.Events != len(events) {
+		t.Errorf("got final progress %+v, want 2 files and %d events", last, len(events))
+	}
+}
+
+func TestLoadProgressCountsUnreadableLogs(t *testing.T) {
+	root := writeProjects(t, map[string]string{
+		"-home-user-proj": `{"cwd":"/home/user/proj","timestamp":"2025-01-02T10:00:00Z","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+	})
+	// A log that's listed but can't be opened
+	if err := os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "-home-user-proj", "b.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+
+	var last Progress
+	ProgressFunc = func(p Progress) { last = p }
+	t.Cleanup(func() { ProgressFunc = nil })
+
+	if _, err := LoadAllEvents(); !IsPartial(err) {
+		t.Fatalf("got error %v, want a partial load", err)
+	}
+	if last.FilesDone != 2 || last.FilesTotal != 2 {
+		t.Errorf("got final progress %+v, want both files done", last)
+	}
+}
+
+func TestListProjectsFromFixtures(t *testing.T) {
+	useFixtureRoots(t)
+
+	projects, err := ListProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range projects {
+		names = append(names, p.Name+"="+p.ActualPath)
+	}
+	if got, want := strings.Join(names, ","), "alpha=/home/dev/alpha,beta=/home/dev/beta"; got != want {
+		t.Errorf("got projects %s, want %s", got, want)
+	}
+}
+
+func TestLoadAllEventsDedupesAcrossProjects(t *testing.T) {
+	useFixtureRoots(t)
+
+	events, err := LoadAllEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for i, e := range events {
+		ids = append(ids, e.EventID)
+		if i > 0 && e.Timestamp.Before(events[i-1].Timestamp) {
+			t.Errorf("events not sorted at %d", i)
+		}
+	}
+	if got, want := strings.Join(ids, ","), "msg_1,msg_2,msg_3,msg_4"; got != want {
+		t.Errorf("got events %s, want %s", got, want)
+	}
+}
+
+func TestExtractUsageEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		want   *UsageEvent
+	}{
+		{
+			name:   "message usage",
+			record: `{"timestamp":"2025-03-01T09:00:00Z","sessionId":"s1","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":1,"output_tokens":2,"cache_creation_input_tokens":3,"cache_read_input_tokens":4}}}`,
+			want: &UsageEvent{
+				Timestamp: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC), InputTokens: 1, OutputTokens: 2,
+				CacheCreation: 3, CacheRead: 4, Model: "claude-sonnet-4-5", Project: "p", EventID: "msg_1", SessionID: "s1",
+			},
+		},
+		{
+			name:   "top-level usage and model",
+			record: `{"ts":1740819600,"model":"claude-haiku-4-5","usage":{"input_tokens":5}}`,
+			want:   &UsageEvent{Timestamp: time.Unix(1740819600, 0), InputTokens: 5, Model: "claude-haiku-4-5", Project: "p"},
+		},
+		{
+			name:   "no timestamp",
+			record: `{"message":{"usage":{"input_tokens":5}}}`,
+		},
+		{
+			name:   "no tokens",
+			record: `{"timestamp":"2025-03-01T09:00:00Z","message":{"usage":{"input_tokens":0}}}`,
+		},
+		{
+			name:   "no usage",
+			record: `{"timestamp":"2025-03-01T09:00:00Z","message":{"content":"hi"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.record), &record); err != nil {
+			t.Fatal(err)
+		}
+		got := extractUsageEvent(record, "p")
+		switch {
+		case tt.want == nil && got != nil:
+			t.Errorf("%s: got %+v, want nil", tt.name, *got)
+		case tt.want != nil && got == nil:
+			t.Errorf("%s: got nil, want %+v", tt.name, *tt.want)
+		case tt.want != nil && (!got.Timestamp.Equal(tt.want.Timestamp) || got.InputTokens != tt.want.InputTokens ||
+			got.OutputTokens != tt.want.OutputTokens || got.CacheCreation != tt.want.CacheCreation ||
+			got.CacheRead != tt.want.CacheRead || got.Model != tt.want.Model || got.Project != tt.want.Project ||
+			got.EventID != tt.want.EventID || got.SessionID != tt.want.SessionID):
+			t.Errorf("%s: got %+v, want %+v", tt.name, *got, *tt.want)
+		}
+	}
+}
+
+func TestIdentifySessionBlocksCreatesGaps(t *testing.T) {
+	start := time.Date(2025, 3, 1, 9, 15, 0, 0, time.UTC)
+	events := []UsageEvent{
+		{Timestamp: start, InputTokens: 1},
+		{Timestamp: start.Add(90 * time.Minute), InputTokens: 2},
+		// Past the 5-hour block, but the last event was under 5 hours ago
+		{Timestamp: start.Add(5*time.Hour + 30*time.Minute), InputTokens: 4},
+		// More than 5 hours after the previous event, leaving a gap
+		{Timestamp: start.Add(12 * time.Hour), InputTokens: 8},
+	}
+
+	blocks := identifySessionBlocks(events, DefaultSessionDuration)
+
+	want := []struct {
+		start  time.Time
+		gap    bool
+		tokens int
+	}{
+		{start, false, 3},
+		{time.Date(2025, 3, 1, 14, 0, 0, 0, time.UTC), false, 4},
+		{start.Add(5*time.Hour + 30*time.Minute + DefaultSessionDuration), true, 0},
+		{time.Date(2025, 3, 1, 21, 0, 0, 0, time.UTC), false, 8},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("got %d blocks, want %d", len(blocks), len(want))
+	}
+	for i, w := range want {
+		b := blocks[i]
+		if !b.StartTime.Equal(w.start) || b.IsGap != w.gap || b.TotalTokens() != w.tokens {
+			t.Errorf("block %d: got start %s gap %v tokens %d, want %s %v %d",
+				i, b.StartTime, b.IsGap, b.TotalTokens(), w.start, w.gap, w.tokens)
+		}
+	}
+}
+
+func TestGenerateFingerprint(t *testing.T) {
+	ts := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	withID := &UsageEvent{Timestamp: ts, InputTokens: 10, Model: "m", EventID: "msg_1"}
+	noID := &UsageEvent{Timestamp: ts, InputTokens: 10, Model: "m"}
+
+	if generateFingerprint(withID, "a.jsonl", 1) != generateFingerprint(withID, "b.jsonl", 7) {
+		t.Error("events with the same ID should match across files and lines")
+	}
+	if generateFingerprint(noID, "a.jsonl", 1) == generateFingerprint(noID, "a.jsonl", 2) {
+		t.Error("events without an ID on different lines should not match")
+	}
+	if generateFingerprint(noID, "a.jsonl", 1) != generateFingerprint(noID, "a.jsonl", 1) {
+		t.Error("the same line read twice should match")
+	}
+	if generateFingerprint(noID, "/p1/a.jsonl", 1) == generateFingerprint(noID, "/p2/a.jsonl", 1) {
+		t.Error("same-named logs in different directories should not match")
+	}
+}
+
+func TestUnparsedTimestampsCounted(t *testing.T) {
+	log := strings.Join([]string{
+		`{"timestamp":"2025-03-01T09:00:00Z","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+		`{"timestamp":1740819660,"message":{"id":"msg_2","usage":{"input_tokens":10}}}`,
+		`{"timestamp":"sometime in March","message":{"id":"msg_3","usage":{"input_tokens":10}}}`,
+	}, "\n")
+
+	// Each log is counted once, however many times it's read
+	before := SkippedTotals().Undated
+	for range 3 {
+		events := parseJSONL(strings.NewReader(log), "mixed.jsonl", make(map[string]bool), "test")
+		if len(events) != 2 {
+			t.Errorf("got %d events, want 2 from the readable timestamps", len(events))
+		}
+		if got := SkippedTotals().Undated - before; got != 1 {
+			t.Errorf("counted %d unparsed timestamps, want 1", got)
+		}
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		val  interface{}
+		want time.Time
+	}{
+		{"2025-03-01T09:00:00Z", want},
+		{"2025-03-01T09:00:00.000Z", want},
+		{"2025-03-01T10:00:00+01:00", want},
+		{float64(want.Unix()), want},
+		{float64(want.UnixMilli()), want},
+		{want.Unix(), want},
+		{want.UnixMilli(), want},
+		{float64(want.UnixMicro()), want},
+		{want.UnixNano(), want},
+		{"2025-03-01 09:00:00Z", want},
+		{"2025-03-01 10:00:00.000+01:00", want},
+		{"2025-03-01T09:00:00", want},
+		{"2025-03-01 09:00:00.000", want},
+		{"Sat, 01 Mar 2025 04:00:00 -0500", want},
+		{strconv.FormatInt(want.Unix(), 10), want},
+		{"yesterday", time.Time{}},
+		{true, time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseTimestamp(tt.val); !got.Equal(tt.want) {
+			t.Errorf("parseTimestamp(%v) = %s, want %s", tt.val, got, tt.want)
+		}
+	}
+}
+
+func TestImplausibleTimestampsSkipped(t *testing.T) {
+	log := strings.Join([]string{
+		`{"timestamp":"2025-03-01T09:00:00Z","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+		`{"timestamp":"2286-11-20T17:46:40Z","message":{"id":"msg_2","usage":{"input_tokens":10}}}`,
+		`{"timestamp":"1970-01-01T00:00:00Z","message":{"id":"msg_3","usage":{"input_tokens":10}}}`,
+		`{"ts":0,"message":{"id":"msg_4","usage":{"input_tokens":10}}}`,
+	}, "\n")
+
+	// Reading the same log again replaces its count rather than adding to it
+	before := SkippedTotals().Timestamps
+	for range 2 {
+		events := parseJSONL(strings.NewReader(log), "implausible.jsonl", make(map[string]bool), "test")
+		if len(events) != 1 || events[0].EventID != "msg_1" {
+			t.Errorf("got %+v, want only msg_1", events)
+		}
+		if got := SkippedTotals().Timestamps - before; got != 3 {
+			t.Errorf("got %d skipped timestamps, want 3", got)
+		}
+	}
+}
+
+func TestAggregateByDurationBuckets(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2025, 3, 1, h, m, 0, 0, time.Local) }
+	events := []UsageEvent{
+		{Timestamp: at(10, 2), InputTokens: 1, Model: "claude-sonnet-4-5"},
+		{Timestamp: at(10, 14), InputTokens: 2, Model: "claude-sonnet-4-5"},
+		{Timestamp: at(10, 15), InputTokens: 4, Model: "claude-sonnet-4-5"},
+		{Timestamp: at(11, 59), InputTokens: 8, Model: "claude-sonnet-4-5"},
+	}
+
+	usage := aggregateByPeriod(events, "15m")
+	want := []struct {
+		period string
+		input  int
+	}{
+		{"2025-03-01 10:00", 3},
+		{"2025-03-01 10:15", 4},
+		{"2025-03-01 11:45", 8},
+	}
+	if len(usage) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(usage), len(want))
+	}
+	for i, w := range want {
+		if usage[i].Period != w.period || usage[i].InputTotal != w.input {
+			t.Errorf("bucket %d = %s with %d input, want %s with %d", i, usage[i].Period, usage[i].InputTotal, w.period, w.input)
+		}
+	}
+
+	for _, group := range []string{"30s", "48h", "day", "-5m"} {
+		if _, ok := BucketDuration(group); ok {
+			t.Errorf("BucketDuration(%q) accepted", group)
+		}
+	}
+}
+
+func TestListProjectsMergesSameCwd(t *testing.T) {
+	// A project renamed from proj-old to proj, with logs under both
+	// directories written from the same working directory
+	writeProjects(t, map[string]string{
+		"-home-user-proj-old": `{"cwd":"/home/user/proj","timestamp":"2025-01-02T10:00:00Z","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+		"-home-user-proj":     `{"cwd":"/home/user/proj","timestamp":"2025-01-03T10:00:00Z","message":{"id":"msg_2","usage":{"input_tokens":20}}}`,
+		"-home-user-other":    `{"cwd":"/home/user/other","timestamp":"2025-01-03T10:00:00Z","message":{"id":"msg_3","usage":{"input_tokens":40}}}`,
+	})
+
+	projects, err := ListProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("got %d projects, want 2", len(projects))
+	}
+	proj := projects[1]
+	if proj.Name != "proj" || len(proj.Dirs()) != 2 {
+		t.Fatalf("got %s with dirs %v, want proj with both directories", proj.Name, proj.Dirs())
+	}
+
+	events, err := LoadProjectEvents(proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sumInput(events); got != 30 {
+		t.Errorf("merged project has %d input tokens, want 30", got)
+	}
+	all, err := LoadAllEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sumInput(all); got != 70 {
+		t.Errorf("all projects have %d input tokens, want 70", got)
+	}
+}
+
+// writeProjects writes each log line under a new root, to a.jsonl in
+// the named project directory or to the named .jsonl file, and points
+// Roots at that root for the rest of the test
+func writeProjects(t *testing.T, logs map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, line := range logs {
+		path := filepath.Join(root, name)
+		if filepath.Ext(name) != ".jsonl" {
+			path = filepath.Join(path, "a.jsonl")
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(line+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	Roots = []string{root}
+	t.Cleanup(func() { Roots = nil })
+	return root
+}
+
+func sumInput(events []UsageEvent) int {
+	total := 0
+	for _, e := range events {
+		total += e.InputTokens
+	}
+	return total
+}
+
+func TestProjectAliases(t *testing.T) {
+	writeProjects(t, map[string]string{
+		"-home-user-api":     `{"cwd":"/home/user/api","timestamp":"2025-01-02T10:00:00Z","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+		"-home-user-old-api": `{"cwd":"/home/user/old/api","timestamp":"2025-01-03T10:00:00Z","message":{"id":"msg_2","usage":{"input_tokens":20}}}`,
+		"-home-user-other":   `{"cwd":"/home/user/other","timestamp":"2025-01-03T10:00:00Z","message":{"id":"msg_3","usage":{"input_tokens":40}}}`,
+	})
+	ProjectAliases = map[string]string{"-home-user-api": "API", "-home-user-old-api": "API"}
+	t.Cleanup(func() { ProjectAliases = nil })
+
+	projects, err := ListProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 2 || projects[0].Name != "API" || len(projects[0].Dirs()) != 2 {
+		t.Fatalf("got %+v, want API merging both api directories, and other", projects)
+	}
+	events, err := LoadProjectEvents(projects[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sumInput(events); got != 30 {
+		t.Errorf("API has %d input tokens, want 30", got)
+	}
+	for _, e := range events {
+		if e.Project != "API" {
+			t.Errorf("event in project %q, want API", e.Project)
+		}
+	}
+}
+
+func TestProjectAliasMatchesOtherName(t *testing.T) {
+	// Aliases naming another directory's project, one found before that
+	// directory and one after it
+	writeProjects(t, map[string]string{
+		"-home-user-api":     `{"cwd":"/home/user/api","timestamp":"2025-01-02T10:00:00Z","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+		"-home-user-old-web": `{"cwd":"/home/user/old/web","timestamp":"2025-01-03T10:00:00Z","message":{"id":"msg_2","usage":{"input_tokens":20}}}`,
+		"-home-user-web":     `{"cwd":"/home/user/web","timestamp":"2025-01-03T10:00:00Z","message":{"id":"msg_3","usage":{"input_tokens":40}}}`,
+		"-home-user-zed":     `{"cwd":"/home/user/zed","timestamp":"2025-01-03T10:00:00Z","message":{"id":"msg_4","usage":{"input_tokens":80}}}`,
+	})
+	ProjectAliases = map[string]string{"-home-user-old-web": "web", "-home-user-zed": "api"}
+	t.Cleanup(func() { ProjectAliases = nil })
+
+	projects, err := ListProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("got %+v, want api and web", projects)
+	}
+	for i, want := range []int{90, 60} {
+		events, err := LoadProjectEvents(projects[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sumInput(events); got != want {
+			t.Errorf("%s has %d input tokens, want %d", projects[i].Name, got, want)
+		}
+	}
+}
+
+func TestProjectDepth(t *testing.T) {
+	// Projects nested as <org>/<repo>, one keeping its logs a level
+	// further down in sessions/
+	writeProjects(t, map[string]string{
+		"acme/api":          `{"cwd":"/src/acme/api","timestamp":"2025-01-02T10:00:00Z","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+		"acme/web/sessions": `{"cwd":"/src/acme/web","timestamp":"2025-01-02T10:00:00Z","message":{"id":"msg_2","usage":{"input_tokens":20}}}`,
+		"personal/blog":     `{"cwd":"/src/blog","timestamp":"2025-01-02T10:00:00Z","message":{"id":"msg_3","usage":{"input_tokens":40}}}`,
+	})
+	ProjectDepth = 2
+	t.Cleanup(func() { ProjectDepth = 1 })
+
+	projects, err := ListProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range projects {
+		names = append(names, p.Name)
+	}
+	if want := []string{"api", "blog", "web"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got projects %v, want %v", names, want)
+	}
+	events, err := LoadProjectEvents(projects[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sumInput(events); got != 20 {
+		t.Errorf("web has %d input tokens, want 20", got)
+	}
+}
+
+// TestFindActualPathStopsEarly gives up on a project whose first logs
+// record no working directory rather than reading every log
+func TestFindActualPathStopsEarly(t *testing.T) {
+	dir := t.TempDir()
+	line := `{"timestamp":"2025-01-02T10:00:00Z","message":{"id":"msg_1","usage":{"input_tokens":10}}}`
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if err := os.WriteFile(filepath.Join(dir, name+".jsonl"), []byte(line+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	withCwd := `{"cwd":"/home/user/proj",` + line[1:]
+	if err := os.WriteFile(filepath.Join(dir, "e.jsonl"), []byte(withCwd+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := findActualPath(dir); got != "" {
+		t.Errorf("got %q, want no working directory after the first %d logs", got, cwdLogs)
+	}
+	if err := os.Rename(filepath.Join(dir, "e.jsonl"), filepath.Join(dir, "0.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+	if got := findActualPath(dir); got != "/home/user/proj" {
+		t.Errorf("got %q, want the first log's working directory", got)
+	}
+}
+
+func TestRecentCutoffSkipsOldLogs(t *testing.T) {
+	root := writeProjects(t, map[string]string{
+		"-home-user-proj/old.jsonl": `{"cwd":"/home/user/proj","timestamp":"2025-01-02T10:00:00Z","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+		"-home-user-proj/new.jsonl": `{"cwd":"/home/user/proj","timestamp":"2025-01-03T10:00:00Z","message":{"id":"msg_2","usage":{"input_tokens":20}}}`,
+	})
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "-home-user-proj", "old.jsonl"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	RecentCutoff = time.Now().Add(-7 * 24 * time.Hour)
+	t.Cleanup(func() { RecentCutoff = time.Time{} })
+
+	events, err := LoadAllEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sumInput(events); got != 20 {
+		t.Errorf("got %d input tokens, want 20 from the recent log only", got)
+	}
+}
+
+func TestProjectForDir(t *testing.T) {
+	projects := []Project{
+		{Name: "repo", ActualPath: "/home/user/repo"},
+		{Name: "tools", ActualPath: "/home/user/repo/tools"},
+		{Name: "repo2", ActualPath: "/home/user/repo2"},
+	}
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"/home/user/repo", "repo"},
+		{"/home/user/repo/src/", "repo"},
+		{"/home/user/repo/tools/bin", "tools"},
+		{"/home/user/repo2", "repo2"},
+		{"/home/user", ""},
+	}
+	for _, tt := range tests {
+		got, ok := ProjectForDir(projects, tt.dir)
+		if ok != (tt.want != "") || got.Name != tt.want {
+			t.Errorf("ProjectForDir(%q) = %q, %v, want %q", tt.dir, got.Name, ok, tt.want)
+		}
+	}
+}
+
+func TestFollowSymlinks(t *testing.T) {
+	// Logs on "another volume", with a link back to their own directory
+	// that must not send the walk round in circles
+	external := t.TempDir()
+	data, err := os.ReadFile(filepath.Join("testdata", "projects", "-home-dev-alpha", "session1.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(external, "session1.jsonl"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(external, filepath.Join(external, "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	if err := os.Symlink(external, filepath.Join(root, "-home-dev-alpha")); err != nil {
+		t.Fatal(err)
+	}
+	Roots = []string{root}
+	t.Cleanup(func() { Roots = nil })
+
+	projects, err := ListProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 0 {
+		t.Fatalf("got %d projects without FollowSymlinks, want 0", len(projects))
+	}
+
+	FollowSymlinks = true
+	t.Cleanup(func() { FollowSymlinks = false })
+
+	projects, err = ListProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 1 {
+		t.Fatalf("got %d projects with FollowSymlinks, want 1", len(projects))
+	}
+	events, err := LoadProjectEvents(projects[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Errorf("got %d events through the symlink, want 3", len(events))
+	}
+}
+
+func TestBusiestHour(t *testing.T) {
+	at := func(day, hour int) time.Time { return time.Date(2025, 3, day, hour, 30, 0, 0, time.Local) }
+	events := []UsageEvent{
+		{Timestamp: at(1, 9), InputTokens: 100},
+		{Timestamp: at(2, 14), InputTokens: 200},
+		{Timestamp: at(3, 14), InputTokens: 300},
+		{Timestamp: at(4, 22), InputTokens: 400},
+	}
+
+	h := BuildHeatmap(events)
+	if h[5][9] != 100 || h[6][14] != 200 {
+		t.Errorf("Saturday 9:00 = %d and Sunday 14:00 = %d, want 100 and 200 in rows 5 and 6", h[5][9], h[6][14])
+	}
+	hours := h.ByHour()
+	if hours[14] != 500 {
+		t.Errorf("hour 14 = %d, want 500 summed across days", hours[14])
+	}
+
+	hour, share, ok := BusiestHour(hours)
+	if !ok || hour != 14 || share != 0.5 {
+		t.Errorf("BusiestHour = %d, %.2f, %v; want 14, 0.50, true", hour, share, ok)
+	}
+	if _, _, ok := BusiestHour([24]int{}); ok {
+		t.Error("BusiestHour with no tokens reported a peak")
+	}
+}
+
+func TestEventCacheKeepsTotalsStable(t *testing.T) {
+	useFixtureRoots(t)
+
+	load := func() (int, int) {
+		t.Helper()
+		events, err := LoadAllEvents()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(events), sumTokens(events)
+	}
+
+	wantEvents, wantTokens := load()
+
+	EventCacheDir = t.TempDir()
+	t.Cleanup(func() { EventCacheDir = "" })
+
+	// The first run fills the cache and the second reads from it; both
+	// must dedupe the event the two fixture projects share
+	for _, run := range []string{"cold", "warm"} {
+		if events, tokens := load(); events != wantEvents || tokens != wantTokens {
+			t.Errorf("%s cache: got %d events, %d tokens; want %d, %d", run, events, tokens, wantEvents, wantTokens)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(EventCacheDir, "events"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("got %d cache entries, want one per fixture log", len(entries))
+	}
+}
+
+// TestEventCacheKeepsSkippedCounts reads a log with a line too long to
+// parse, an implausible date and a missing one through a cold and a warm
+// cache
+func TestEventCacheKeepsSkippedCounts(t *testing.T) {
+	MaxLineSize = 1 << 10
+	EventCacheDir = t.TempDir()
+	t.Cleanup(func() { MaxLineSize, EventCacheDir = 8<<20, "" })
+
+	path := filepath.Join(t.TempDir(), "skips.jsonl")
+	log := strings.Join([]string{
+		`{"timestamp":"2025-03-01T09:00:00Z","message":{"id":"a","usage":{"input_tokens":10}}}`,
+		`{"timestamp":"2025-03-01T09:01:00Z","padding":"` + strings.Repeat("x", 2000) + `","message":{"id":"b","usage":{"input_tokens":5}}}`,
+		`{"timestamp":"2286-11-20T17:46:40Z","message":{"id":"c","usage":{"input_tokens":20}}}`,
+		`{"message":{"id":"d","usage":{"input_tokens":40}}}`,
+	}, "\n")
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	before := SkippedTotals()
+	for _, run := range []string{"cold", "warm"} {
+		events, err := parseJSONLFile(path, make(map[string]bool), "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := SkippedTotals()
+		if len(events) != 1 || got.Lines-before.Lines != 1 || got.Timestamps-before.Timestamps != 1 ||
+			got.Undated-before.Undated != 1 {
+			t.Errorf("%s cache: got %d events, %d long lines, %d implausible and %d missing timestamps; want 1 of each",
+				run, len(events), got.Lines-before.Lines, got.Timestamps-before.Timestamps, got.Undated-before.Undated)
+		}
+	}
+}
+
+func TestRawModels(t *testing.T) {
+	events := []UsageEvent{
+		{Timestamp: time.Date(2025, 3, 1, 9, 0, 0, 0, time.Local), InputTokens: 1, Model: "claude-opus-4-20250514"},
+		{Timestamp: time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local), InputTokens: 2, Model: "claude-opus-4-1-20250805"},
+		{Timestamp: time.Date(2025, 3, 1, 11, 0, 0, 0, time.Local), InputTokens: 4},
+	}
+
+	usage := aggregateByPeriod(events, "day")
+	if got := strings.Join(usage[0].Models, ","); got != "opus-4,opus-4-1,unknown" {
+		t.Errorf("normalized models = %s", got)
+	}
+
+	RawModels = true
+	t.Cleanup(func() { RawModels = false })
+
+	usage = aggregateByPeriod(events, "day")
+	if got := strings.Join(usage[0].Models, ","); got != "claude-opus-4-1-20250805,claude-opus-4-20250514,unknown" {
+		t.Errorf("raw models = %s", got)
+	}
+}
+
+func TestConsoleExport(t *testing.T) {
+	for _, name := range []string{"console_export.json", "console_report.json"} {
+		read, err := readLogFileUncached(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed := read.Events
+		if len(parsed) != 2 {
+			t.Fatalf("%s: got %d events, want 2 (rows without tokens skipped)", name, len(parsed))
+		}
+
+		first := parsed[0].Event
+		if first.InputTokens != 1000 || first.OutputTokens != 400 || first.CacheCreation != 200 || first.CacheRead != 3000 {
+			t.Errorf("%s: first event tokens = %+v", name, first)
+		}
+		if first.Model != "claude-sonnet-4-5-20250929" || first.Timestamp.IsZero() {
+			t.Errorf("%s: first event model %q at %s", name, first.Model, first.Timestamp)
+		}
+		if parsed[0].Fingerprint == parsed[1].Fingerprint {
+			t.Errorf("%s: rows share a fingerprint", name)
+		}
+	}
+
+	// Content sniffing picks the Console parser on stdin
+	for _, name := range []string{"console_export.json", "console_report.json"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if events := ParseEvents(strings.NewReader("\n  "+string(data)), "stdin"); len(events) != 2 {
+			t.Errorf("got %d events from %s on stdin, want 2", len(events), name)
+		}
+	}
+}
+
+func TestAggregateByRole(t *testing.T) {
+	input := strings.Join([]string{
+		`{"timestamp":"2025-03-01T09:00:00Z","message":{"id":"a","role":"assistant","usage":{"input_tokens":10,"output_tokens":5}}}`,
+		`{"timestamp":"2025-03-01T09:01:00Z","role":"user","usage":{"input_tokens":100}}`,
+		`{"timestamp":"2025-03-01T09:02:00Z","message":{"id":"b","usage":{"input_tokens":1}}}`,
+	}, "\n")
+
+	events := ParseEvents(strings.NewReader(input), "test")
+	usage := LoadGroupedUsageForEvents(events, "role")
+
+	want := map[string]int{"assistant": 10, "unknown": 1, "user": 100}
+	if len(usage) != len(want) {
+		t.Fatalf("got %d roles, want %d", len(usage), len(want))
+	}
+	for _, u := range usage {
+		if u.InputTotal != want[u.Period] {
+			t.Errorf("role %q input = %d, want %d", u.Period, u.InputTotal, want[u.Period])
+		}
+	}
+}
+
+func TestKeepZero(t *testing.T) {
+	input := strings.Join([]string{
+		`{"timestamp":"2025-03-01T09:00:00Z","message":{"id":"a","usage":{"input_tokens":10}}}`,
+		`{"timestamp":"2025-03-01T09:01:00Z","message":{"id":"b","usage":{"input_tokens":0,"output_tokens":0}}}`,
+	}, "\n")
+
+	if events := ParseEvents(strings.NewReader(input), "test"); len(events) != 1 {
+		t.Fatalf("got %d events by default, want the zero-token record dropped", len(events))
+	}
+
+	KeepZero = true
+	t.Cleanup(func() { KeepZero = false })
+
+	events := ParseEvents(strings.NewReader(input), "test")
+	if len(events) != 2 {
+		t.Fatalf("got %d events with KeepZero, want 2", len(events))
+	}
+	usage := aggregateByPeriod(events, "day")
+	if len(usage) != 1 || usage[0].ZeroEvents != 1 || usage[0].InputTotal != 10 {
+		t.Errorf("got %+v, want one day with 10 input tokens and 1 zero-token event", usage)
+	}
+}
+
+// TestMaxLineSize skips a line too long to parse without losing the lines
+// around it
+func TestMaxLineSize(t *testing.T) {
+	MaxLineSize = 1 << 10
+	t.Cleanup(func() { MaxLineSize = 8 << 20 })
+
+	long := `{"timestamp":"2025-03-01T09:01:00Z","padding":"` + strings.Repeat("x", 10000) + `","message":{"id":"b","usage":{"input_tokens":5}}}`
+	input := strings.Join([]string{
+		`{"timestamp":"2025-03-01T09:00:00Z","message":{"id":"a","usage":{"input_tokens":10}}}`,
+		long,
+		`{"timestamp":"2025-03-01T09:02:00Z","message":{"id":"c","usage":{"input_tokens":20}}}`,
+	}, "\n")
+
+	before := SkippedTotals().Lines
+	events := ParseEvents(strings.NewReader(input), "test")
+	if len(events) != 2 || events[0].InputTokens != 10 || events[1].InputTokens != 20 {
+		t.Fatalf("got %+v, want the two short lines", events)
+	}
+	if n := SkippedTotals().Lines - before; n != 1 {
+		t.Errorf("got %d skipped lines, want 1", n)
+	}
+}
+
+// TestCcusageCompatibility reads a Claude Code line in the shape ccusage
+// documents: usage on message.usage, and the event keyed by message.id and
+// requestId
+func TestCcusageCompatibility(t *testing.T) {
+	events, err := parseJSONLFile(filepath.Join("testdata", "ccusage_sample.jsonl"), make(map[string]bool), "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want the repeated line deduplicated to 1", len(events))
+	}
+
+	e := events[0]
+	want := UsageEvent{
+		Timestamp:     time.Date(2025, 6, 21, 9, 30, 12, 345e6, time.UTC),
+		InputTokens:   4,
+		OutputTokens:  120,
+		CacheCreation: 6148,
+		CacheRead:     10524,
+		Model:         "claude-sonnet-4-20250514",
+		Project:       "project",
+		EventID:       "msg_01XyZAbCdEfGhIjKlMnOpQrS:req_011CQaBcDeFgHiJkLmNoPqRs",
+		SessionID:     "0f6a2c4e-8b1d-4e77-a0c5-5d2e9b3f7c21",
+		Role:          "assistant",
+		SourceFile:    "ccusage_sample.jsonl",
+	}
+	if !e.Timestamp.Equal(want.Timestamp) {
+		t.Errorf("timestamp = %s, want %s", e.Timestamp, want.Timestamp)
+	}
+	e.Timestamp = want.Timestamp
+	if e != want {
+		t.Errorf("got %+v\nwant %+v", e, want)
+	}
+}
+
+// TestTail reads events as they're appended, holding back a half-written
+// line, and starts over without repeats when the log is replaced
+func TestTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	write := func(flag int, s string) {
+		t.Helper()
+		f, err := os.OpenFile(path, flag|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.WriteString(s); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	poll := func(tail *Tail, want ...int) {
+		t.Helper()
+		events, err := tail.Poll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, e := range events {
+			got = append(got, e.InputTokens)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got input tokens %v, want %v", got, want)
+		}
+	}
+	a := `{"timestamp":"2025-03-01T09:00:00Z","message":{"id":"a","usage":{"input_tokens":1}}}` + "\n"
+	b := `{"timestamp":"2025-03-01T09:01:00Z","message":{"id":"b","usage":{"input_tokens":2}}}` + "\n"
+	c := `{"timestamp":"2025-03-01T09:02:00Z","message":{"usage":{"input_tokens":3}}}` + "\n"
+
+	write(os.O_CREATE, a)
+	tail := NewTail(path, "project")
+	poll(tail, 1)
+	poll(tail)
+
+	write(os.O_APPEND, b[:20])
+	poll(tail)
+	write(os.O_APPEND, b[20:])
+	poll(tail, 2)
+
+	// Replaced by a shorter log that repeats a
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	write(os.O_CREATE, a+c)
+	poll(tail, 3)
+}
+
+// TestDropSmallSessions removes sessions under the threshold and joins the
+// gaps that end up next to each other
+func TestDropSmallSessions(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2025, 3, 1, h, 0, 0, 0, time.UTC) }
+	blocks := []SessionBlock{
+		{ID: "big", StartTime: at(0), EndTime: at(5), InputTokens: 5000},
+		{ID: "gap-1", StartTime: at(5), EndTime: at(8), IsGap: true},
+		{ID: "small", StartTime: at(8), EndTime: at(13), InputTokens: 200},
+		{ID: "gap-2", StartTime: at(13), EndTime: at(20), IsGap: true},
+		{ID: "big-2", StartTime: at(20), EndTime: at(25), InputTokens: 1000},
+	}
+
+	got := DropSmallSessions(blocks, 1000)
+	var ids []string
+	for _, b := range got {
+		ids = append(ids, b.ID)
+	}
+	if want := []string{"big", "gap-1", "big-2"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("got %v, want %v", ids, want)
+	}
+	if !got[1].StartTime.Equal(at(5)) || !got[1].EndTime.Equal(at(20)) {
+		t.Errorf("merged gap spans %v to %v, want %v to %v", got[1].StartTime, got[1].EndTime, at(5), at(20))
+	}
+
+	kept, dropped := SplitMinTokens([]GroupedUsage{{Period: "a", InputTotal: 10}, {Period: "b", InputTotal: 1000}}, 1000)
+	if len(kept) != 1 || kept[0].Period != "b" || len(dropped) != 1 || dropped[0].Period != "a" {
+		t.Errorf("SplitMinTokens kept %+v, dropped %+v", kept, dropped)
+	}
+}
+
+// TestDetectSessionDuration finds the window length that work keeps
+// resuming just after, and gives up without enough breaks
+func TestDetectSessionDuration(t *testing.T) {
+	start := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	var events []UsageEvent
+	// Two hours of work, then a wait until 5 minutes after a 3-hour reset
+	for burst := 0; burst < 5; burst++ {
+		for m := 0; m <= 120; m += 10 {
+			events = append(events, UsageEvent{Timestamp: start.Add(time.Duration(m) * time.Minute), InputTokens: 100})
+		}
+		start = start.Add(3*time.Hour + 5*time.Minute)
+	}
+
+	if got := DetectSessionDuration(events); got != 3*time.Hour {
+		t.Errorf("DetectSessionDuration() = %v, want 3h", got)
+	}
+	if got := DetectSessionDuration(events[:13]); got != 0 {
+		t.Errorf("DetectSessionDuration() of one burst = %v, want 0", got)
+	}
+}
+
+// TestUTC moves timestamps logged with an offset to UTC and labels times
+// of day in ISO 8601
+func TestUTC(t *testing.T) {
+	UTC = true
+	t.Cleanup(func() { UTC = false })
+
+	input := `{"timestamp":"2025-03-01T11:30:00+02:00","message":{"id":"a","usage":{"input_tokens":10}}}`
+	events := ParseEvents(strings.NewReader(input), "test")
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	ts := events[0].Timestamp
+	if ts.Location() != time.UTC || ts.Hour() != 9 {
+		t.Errorf("got timestamp %v, want 09:30 UTC", ts)
+	}
+	if got := formatPeriod(ts, "hour"); got != "2025-03-01T09:00Z" {
+		t.Errorf("hour period = %q, want 2025-03-01T09:00Z", got)
+	}
+	if got := formatPeriod(ts, "15m"); got != "2025-03-01T09:30Z" {
+		t.Errorf("15m period = %q, want 2025-03-01T09:30Z", got)
+	}
+}
+
+// TestDiffUsage reports periods added, removed and changed, skipping those
+// with the same tokens. Cache writes moving to the 1-hour cache count as a
+// change.
+func TestDiffUsage(t *testing.T) {
+	before := []GroupedUsage{
+		{Period: "2025-03-01", InputTotal: 100},
+		{Period: "2025-03-02", InputTotal: 200},
+		{Period: "2025-03-03", InputTotal: 300},
+		{Period: "2025-03-05", CacheCreateTotal: 60},
+	}
+	after := []GroupedUsage{
+		{Period: "2025-03-02", InputTotal: 200},
+		{Period: "2025-03-03", InputTotal: 350},
+		{Period: "2025-03-04", OutputTotal: 40},
+		{Period: "2025-03-05", CacheCreateTotal: 60, CacheCreate1h: 60},
+	}
+
+	diffs := DiffUsage(before, after)
+	var got []string
+	for _, d := range diffs {
+		got = append(got, fmt.Sprintf("%s %+d", d.Period, d.Delta()))
+	}
+	want := []string{"2025-03-01 -100", "2025-03-03 +50", "2025-03-04 +40", "2025-03-05 +0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if diffs[0].After != nil || diffs[2].Before != nil {
+		t.Error("removed and added periods should have no After and Before")
+	}
+}
@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+)
+
+// LatestLog returns the most recently written log in the given projects and
+// the project it belongs to, which is where an active session is logging
+func LatestLog(projects []Project) (string, Project, error) {
+	var latest string
+	var project Project
+	var modTime int64
+	for _, p := range projects {
+		for _, dir := range p.Dirs() {
+			walkLogs(dir, func(path string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() || !isLogFile(path) {
+					return nil
+				}
+				if t := info.ModTime().UnixNano(); latest == "" || t > modTime {
+					latest, project, modTime = path, p, t
+				}
+				return nil
+			})
+		}
+	}
+	if latest == "" {
+		return "", Project{}, errors.New("no logs found")
+	}
+	return latest, project, nil
+}
+
+// Tail follows a log as it's written. Each Poll returns the usage events
+// appended since the last.
+type Tail struct {
+	Path    string
+	Project string
+
+	info    os.FileInfo
+	offset  int64
+	line    int
+	seen    map[string]bool
+	skipped Skipped
+}
+
+// NewTail follows the log at path, attributing its events to project. The
+// first Poll returns the events already in the log.
+func NewTail(path, project string) *Tail {
+	return &Tail{Path: path, Project: project, seen: make(map[string]bool)}
+}
+
+// Poll reads the lines completed since the last poll. A last line still
+// being written is left for the next poll. If the log was replaced or
+// truncated, it's read again from the start, skipping events already
+// returned.
+func (t *Tail) Poll() ([]UsageEvent, error) {
+	info, err := os.Stat(t.Path)
+	if err != nil {
+		return nil, err
+	}
+	if t.info != nil && (!os.SameFile(t.info, info) || info.Size() < t.offset) {
+		t.offset, t.line, t.skipped = 0, 0, Skipped{}
+	}
+	t.info = info
+	if info.Size() == t.offset {
+		return nil, nil
+	}
+
+	file, err := os.Open(t.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Counts carry across polls, as they're recorded for the whole log
+	read := logRead{Skipped: t.skipped}
+	reader := bufio.NewReader(file)
+	for {
+		line, tooLong, err := readLine(reader)
+		if err == io.EOF {
+			// A line without its newline may be half written
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		pos, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		t.offset = pos - int64(reader.Buffered())
+		t.line++
+
+		if tooLong {
+			read.Skipped.Lines++
+			continue
+		}
+		event, undated := parseLine(line, "")
+		if event != nil {
+			read.Events = append(read.Events, fileEvent{
+				Event:       *event,
+				Fingerprint: generateFingerprint(event, t.Path, t.line),
+			})
+		} else if undated {
+			read.Skipped.Undated++
+		}
+	}
+
+	events := dedupeEvents(&read, t.Path, t.seen, t.Project)
+	t.skipped = read.Skipped
+	return events, nil
+}
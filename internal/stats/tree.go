@@ -0,0 +1,98 @@
+package stats
+
+import (
+	"slices"
+	"sort"
+)
+
+// UsageNode is one level of a hierarchical usage breakdown, e.g. a period
+// containing projects containing models
+type UsageNode struct {
+	Key         string
+	Input       int
+	Output      int
+	CacheCreate int
+	CacheRead   int
+	Requests    int // Usage records, roughly one per API request
+	Children    []*UsageNode
+}
+
+// TotalTokens returns all tokens in the node
+func (n *UsageNode) TotalTokens() int {
+	return n.Input + n.Output + n.CacheCreate + n.CacheRead
+}
+
+// AggregateTree groups events by each level in turn. Levels may be a time
+// period (hour, day, week, month, year), "project", "session" or "model".
+// Periods and sessions keep chronological order, weekdays run Monday to
+// Sunday, and projects, roles and models are sorted by name.
+func AggregateTree(events []UsageEvent, levels []string) []*UsageNode {
+	if len(levels) == 0 {
+		return nil
+	}
+
+	level := levels[0]
+	if level == "session" {
+		events = withSessionIDs(events)
+	}
+	nodes := make(map[string]*UsageNode)
+	grouped := make(map[string][]UsageEvent)
+	var keys []string
+
+	for _, e := range events {
+		k := levelKey(e, level)
+		n, ok := nodes[k]
+		if !ok {
+			n = &UsageNode{Key: k}
+			nodes[k] = n
+			keys = append(keys, k)
+		}
+		n.Input += e.InputTokens
+		n.Output += e.OutputTokens
+		n.CacheCreate += e.CacheCreation
+		n.CacheRead += e.CacheRead
+		n.Requests++
+		grouped[k] = append(grouped[k], e)
+	}
+
+	switch level {
+	case "project", "role", "model":
+		sort.Strings(keys)
+	case "session":
+		slices.SortStableFunc(keys, sessionOrder(events))
+	default:
+		slices.SortStableFunc(keys, periodOrder(level))
+	}
+
+	result := make([]*UsageNode, len(keys))
+	for i, k := range keys {
+		n := nodes[k]
+		n.Children = AggregateTree(grouped[k], levels[1:])
+		result[i] = n
+	}
+	return result
+}
+
+func levelKey(e UsageEvent, level string) string {
+	switch level {
+	case "project":
+		if e.Project == "" {
+			return "unknown"
+		}
+		return e.Project
+	case "session":
+		if e.SessionID == "" {
+			return "unknown"
+		}
+		return e.SessionID
+	case "role":
+		if e.Role == "" {
+			return "unknown"
+		}
+		return e.Role
+	case "model":
+		return displayModelName(e.Model)
+	default:
+		return formatPeriod(e.Timestamp.Local(), level)
+	}
+}
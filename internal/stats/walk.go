@@ -0,0 +1,90 @@
+package stats
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FollowSymlinks makes project listing and log walks follow symlinked
+// directories and files, e.g. logs kept on another volume and linked into
+// the projects directory. Each real directory is visited once, so link
+// cycles end.
+var FollowSymlinks bool
+
+// RecentCutoff, when set, skips logs last written before it without
+// opening them, for a quick look at recent usage in a long history
+var RecentCutoff time.Time
+
+// recentLog reports whether the walked path is a log to read: a log file,
+// written since RecentCutoff when that is set
+func recentLog(path string, info os.FileInfo) bool {
+	if info.IsDir() || !isLogFile(path) {
+		return false
+	}
+	return RecentCutoff.IsZero() || !info.ModTime().Before(RecentCutoff)
+}
+
+// walkLogs walks the tree under root like filepath.Walk, following
+// symlinks when FollowSymlinks is set. Paths passed to fn stay under root.
+func walkLogs(root string, fn filepath.WalkFunc) error {
+	if !FollowSymlinks {
+		return filepath.Walk(root, fn)
+	}
+	err := walkFollowing(root, make(map[string]bool), fn)
+	if errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func walkFollowing(path string, visited map[string]bool, fn filepath.WalkFunc) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fn(path, nil, err)
+	}
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fn(path, info, err)
+	}
+	if visited[real] {
+		return nil
+	}
+	visited[real] = true
+
+	if err := fn(path, info, nil); err != nil {
+		if errors.Is(err, filepath.SkipDir) {
+			return nil
+		}
+		return err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return fn(path, info, err)
+	}
+	for _, entry := range entries {
+		if err := walkFollowing(filepath.Join(path, entry.Name()), visited, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isDirEntry reports whether entry, found in dir, is a directory, counting
+// symlinks to directories when FollowSymlinks is set
+func isDirEntry(dir string, entry os.DirEntry) bool {
+	if entry.IsDir() {
+		return true
+	}
+	if !FollowSymlinks || entry.Type()&os.ModeSymlink == 0 {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, entry.Name()))
+	return err == nil && info.IsDir()
+}
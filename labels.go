@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sessionLabels maps session block IDs to the labels given to them
+type sessionLabels map[string]string
+
+// labelsPath returns the file session labels are saved to
+func labelsPath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "labels.json"), nil
+}
+
+// loadLabels reads the saved session labels, returning none when there
+// are none yet
+func loadLabels() sessionLabels {
+	labels := make(sessionLabels)
+	path, err := labelsPath()
+	if err != nil {
+		return labels
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return labels
+	}
+	json.Unmarshal(data, &labels)
+	return labels
+}
+
+// save writes the labels, replacing those saved before
+func (l sessionLabels) save() error {
+	path, err := labelsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// labelKey is the key a block's label is saved under. Block IDs are their
+// start time in the local zone, so it's moved to UTC to keep labels when
+// --tz or --utc change.
+func labelKey(id string) string {
+	t, err := time.Parse(time.RFC3339, id)
+	if err != nil {
+		return id
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (l sessionLabels) get(id string) string {
+	return l[labelKey(id)]
+}
+
+// set labels a block, or removes its label when label is blank
+func (l sessionLabels) set(id, label string) {
+	if label = strings.TrimSpace(label); label == "" {
+		delete(l, labelKey(id))
+		return
+	}
+	l[labelKey(id)] = label
+}
+
+// startLabeling opens the label input for the selected session
+func (m *model) startLabeling() tea.Cmd {
+	item, ok := m.list.SelectedItem().(sessionItem)
+	if !ok || item.block.IsGap {
+		return nil
+	}
+	m.labelInput = textinput.New()
+	m.labelInput.Prompt = "Label: "
+	m.labelInput.Placeholder = "e.g. the big refactor"
+	m.labelInput.CharLimit = 80
+	m.labelInput.SetValue(m.labels.get(item.block.ID))
+	m.labelInput.CursorEnd()
+	m.labelInput.Cursor.SetMode(cursor.CursorStatic)
+	m.labeling = true
+	return m.labelInput.Focus()
+}
+
+// updateLabeling handles keys while the label input is open: enter saves
+// the label, esc cancels, and anything else edits it
+func (m *model) updateLabeling(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.labeling = false
+		return nil
+	case tea.KeyEnter:
+		m.labeling = false
+		item, ok := m.list.SelectedItem().(sessionItem)
+		if !ok {
+			return nil
+		}
+		m.labels.set(item.block.ID, m.labelInput.Value())
+		cmd := m.list.SetItems(m.sessionItems())
+		if err := m.labels.save(); err != nil {
+			m.notice = "saving label failed: " + err.Error()
+			return tea.Batch(cmd, clearNotice())
+		}
+		return cmd
+	}
+	var cmd tea.Cmd
+	m.labelInput, cmd = m.labelInput.Update(msg)
+	return cmd
+}
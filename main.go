@@ -2,14 +2,29 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/table"
@@ -20,31 +35,231 @@ import (
 // version is set at build time via ldflags
 var version = "devel"
 
+// pricing holds the model rates used for cost estimates
+var pricing = stats.DefaultPricing()
+
 // CLI defines the command-line interface
 var CLI struct {
-	JSON    bool   `short:"j" help:"Output data as JSON instead of TUI"`
-	Project string `short:"p" help:"Filter to specific project"`
-	Group   string `short:"g" enum:"hour,day,week,month,year" default:"day" help:"Group by time period (hour, day, week, month, year)"`
-	Version kong.VersionFlag `short:"v" help:"Show version"`
+	JSON            bool              `short:"j" help:"Output data as JSON instead of TUI"`
+	Project         string            `short:"p" help:"Filter to specific project"`
+	Here            bool              `help:"Filter to the project for the current directory, opening it in the TUI; all projects when none matches"`
+	ExcludeProject  []string          `help:"Leave out projects whose name matches this glob (e.g. tmp-*). Repeatable"`
+	Group           string            `short:"g" default:"day" help:"Group by time period (minute, hour, day, week, month, quarter, year), weekday across all weeks, all for one total, a duration such as 15m, session or role. Comma-separate levels, e.g. day,project,model, for nested JSON. The TUI uses a single period and otherwise day"`
+	Stdin           bool              `help:"Read JSONL usage logs, or an Anthropic Console JSON export, from stdin instead of project directories"`
+	Compact         bool              `help:"Output JSON on a single line without indentation"`
+	RawModels       bool              `help:"Show model identifiers as logged, e.g. claude-opus-4-20250514, instead of normalized names"`
+	NoModels        bool              `help:"Omit the per-model breakdown, leaving only period totals"`
+	Reverse         bool              `help:"List the most recent period first"`
+	Cumulative      bool              `help:"Add a running total of tokens through each period; requires chronological order"`
+	MergeCache      bool              `help:"Show cache writes and reads as one Cache column, and one cache field in JSON"`
+	KeepZero        bool              `help:"Keep usage records with no tokens, counting them per period, for debugging"`
+	CarryModel      bool              `help:"Attribute usage records without a model to the last model seen earlier in the same log, rather than to unknown"`
+	MinTokens       int               `help:"Hide periods and sessions with fewer tokens than this; totals still count them"`
+	ExcludeFiltered bool              `help:"Leave usage hidden by --min-tokens out of totals too"`
+	Verbose         bool              `help:"Report events skipped while parsing on stderr"`
+	MaxLineSize     int               `default:"8" help:"Skip log lines longer than this many MB, counting them in a warning; 0 for no limit"`
+	Recent          string            `help:"Only read logs written in this long, in days (7d) or a duration (12h), skipping older ones unopened; speeds up status on long histories"`
+	PerFile         bool              `help:"Treat each JSONL log file as its own project"`
+	Limit           int               `help:"Token budget per session window, shown as a gauge in status and the TUI"`
+	SessionDuration time.Duration     `default:"5h" help:"Length of the usage limit window that session blocks follow; status suggests one detected from your usage"`
+	ActiveThreshold time.Duration     `help:"Inactivity after which a session stops counting as active (e.g. 30m). Default: the session window length"`
+	TZ              string            `env:"CLAUDETTE_TZ" help:"Time zone used for period labels (e.g. America/New_York)"`
+	UTC             bool              `help:"Use UTC for period labels and JSON timestamps, with ISO 8601 times of day; overrides --tz"`
+	Roots           []string          `env:"CLAUDETTE_ROOTS" sep:":" help:"Directories to scan for projects, separated by ':'"`
+	Depth           int               `default:"1" help:"How many directories below each root projects are, e.g. 2 for <org>/<repo> layouts"`
+	Alias           map[string]string `help:"Rename the project in a directory under the roots, e.g. --alias=-home-me-src-api=API; directories given the same name are merged"`
+	FollowSymlinks  bool              `help:"Follow symlinked project directories and logs, e.g. logs kept on another drive"`
+	Cache           bool              `help:"Cache parsed logs in the cache directory so unchanged logs aren't parsed again"`
+	CacheDir        string            `env:"CLAUDETTE_CACHE_DIR" help:"Directory for cached state"`
+	Dense           bool              `help:"Draw TUI tables without lines between rows, fitting more rows on screen"`
+	ConfirmQuit     bool              `help:"Ask for a second q before quitting the TUI"`
+	Resume          bool              `help:"Reopen the TUI at the view and selection it was last closed on"`
+	IncludeEmpty    bool              `help:"Show projects without any usage in the TUI's project list. TUI only: JSON output always lists every project"`
+	ThousandsSep    string            `default:"," help:"Separator between digit groups in token counts, e.g. '.' or ' '"`
+	Units           string            `default:"auto" enum:"k,m,raw,auto" help:"Units for token counts in TUI tables and status: k, m, raw for full counts, or auto to shorten them on narrow terminals"`
+	Theme           string            `default:"dark" enum:"dark,light,mono" env:"CLAUDETTE_THEME" help:"Color theme: dark, light, or mono for text attributes only"`
+	NoColor         bool              `help:"Disable colors and styling, e.g. for piping; also set by the NO_COLOR environment variable"`
+	Pricing         string            `type:"path" help:"JSON file of per-model rates (USD per million tokens) overriding the defaults; CLAUDETTE_PRICING_JSON, inline JSON, takes precedence"`
+	Version         kong.VersionFlag  `short:"v" help:"Show version"`
+	CPUProfile      string            `name:"cpuprofile" type:"path" hidden:"" help:"Write a pprof CPU profile of the run to this file"`
+	MemProfile      string            `name:"memprofile" type:"path" hidden:"" help:"Write a pprof heap profile at the end of the run to this file"`
 
 	Projects struct {
 		List struct{} `cmd:"" help:"List available projects"`
 	} `cmd:"" help:"Manage projects"`
 
-	Status struct{} `cmd:"" help:"Show current session status"`
+	Status struct {
+		Every    time.Duration `help:"Clear the screen and reprint status on this interval (e.g. 10s) until interrupted"`
+		Live     bool          `help:"Check for a running Claude Code process to tell live sessions from recent ones"`
+		NotifyAt []string      `help:"With --every, send a desktop notification when the active session reaches this many tokens, or this cost with a leading $ (e.g. '$5'); repeatable or comma-separated"`
+	} `cmd:"" help:"Show current session status"`
+
+	Tail struct {
+		Interval time.Duration `default:"1s" help:"How often to check the log for new events"`
+	} `cmd:"" help:"Follow the active session's log, printing each usage event as it arrives"`
+
+	Daily struct{} `cmd:"" help:"Show daily usage by model"`
+
+	Summary struct {
+		Days int `default:"7" help:"Number of recent days whose average cost drives the monthly projection"`
+	} `cmd:"" help:"Show all-time totals and this month's cost so far and projected"`
+
+	Heatmap struct{} `cmd:"" help:"Show token usage by day of week and hour of day"`
+
+	Models struct {
+		List struct{} `cmd:"" help:"List every model seen in the logs with its usage"`
+	} `cmd:"" help:"Inspect models"`
+
+	Doctor struct{} `cmd:"" help:"Report duplicate logs across search roots and their token impact"`
+
+	Events struct {
+		CSV bool `name:"csv" help:"Print events as CSV"`
+	} `cmd:"" help:"Print every usage event, without aggregating, for analysis elsewhere"`
+
+	Report struct {
+		Text bool `help:"Print a plain-text summary of the last 7 days instead, for pasting into chat or email"`
+	} `cmd:"" help:"Print estimated cost per period as CSV, e.g. --group month for expenses"`
+
+	Snapshot struct {
+		Save struct {
+			File string `arg:"" type:"path" help:"File to write the snapshot to"`
+		} `cmd:"" help:"Save current usage, as printed by --json, to a file"`
+		Diff struct {
+			File string `arg:"" type:"path" help:"Snapshot file to compare with"`
+		} `cmd:"" help:"Show the periods added, removed or changed since a snapshot"`
+	} `cmd:"" help:"Save usage snapshots and compare against them"`
+
+	Schema struct {
+		Output string `arg:"" optional:"" default:"usage" help:"JSON output to describe: usage, tree, daily, models, session, status, doctor, diff or heatmap"`
+	} `cmd:"" help:"Print the JSON Schema of a --json output"`
+
+	Config struct {
+		Path struct{} `cmd:"" help:"Print the config file location"`
+	} `cmd:"" help:"Manage configuration"`
 
 	TUI struct{} `cmd:"" default:"1" help:"Start the interactive TUI (default)"`
 }
 
 func main() {
+	cfg, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	ctx := kong.Parse(&CLI,
 		kong.Name("claudette"),
 		kong.Description("Claude Code usage statistics viewer"),
 		kong.UsageOnError(),
 		kong.Vars{"version": version},
+		kong.Resolvers(cfg),
+		kong.Exit(exit),
 	)
 
+	ctx.FatalIfErrorf(setTheme(CLI.Theme, CLI.NoColor))
+
+	if CLI.TZ != "" {
+		loc, err := time.LoadLocation(CLI.TZ)
+		ctx.FatalIfErrorf(err)
+		time.Local = loc
+	}
+	if CLI.UTC {
+		time.Local = time.UTC
+		stats.UTC = true
+	}
+	stats.Roots = CLI.Roots
+	if CLI.Depth < 1 {
+		ctx.Fatalf("--depth must be at least 1")
+	}
+	stats.ProjectDepth = CLI.Depth
+	stats.ProjectAliases = CLI.Alias
+	stats.ThousandsSep = CLI.ThousandsSep
+	stats.PerFile = CLI.PerFile
+	stats.ActiveThreshold = CLI.ActiveThreshold
+	if CLI.SessionDuration <= 0 {
+		ctx.Fatalf("--session-duration must be positive")
+	}
+	for _, pattern := range CLI.ExcludeProject {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			ctx.Fatalf("invalid --exclude-project pattern %q: %v", pattern, err)
+		}
+	}
+	stats.ExcludeProjects = CLI.ExcludeProject
+	stats.FollowSymlinks = CLI.FollowSymlinks
+	stats.RawModels = CLI.RawModels
+	stats.KeepZero = CLI.KeepZero
+	stats.CarryModel = CLI.CarryModel
+	if CLI.MaxLineSize < 0 {
+		ctx.Fatalf("--max-line-size must not be negative")
+	}
+	stats.MaxLineSize = CLI.MaxLineSize << 20
+	if CLI.Recent != "" {
+		age, err := parseAge(CLI.Recent)
+		if err != nil {
+			ctx.Fatalf("invalid --recent %q: %v", CLI.Recent, err)
+		}
+		stats.RecentCutoff = time.Now().Add(-age)
+	}
+	if CLI.Cache {
+		dir, err := cacheDir()
+		ctx.FatalIfErrorf(err)
+		stats.EventCacheDir = dir
+	}
+
+	pricing, err = stats.LoadPricing(CLI.Pricing)
+	ctx.FatalIfErrorf(err)
+
+	ctx.FatalIfErrorf(validateGroup(CLI.Group))
+	if CLI.Cumulative && CLI.Reverse {
+		ctx.Fatalf("--cumulative needs chronological order and can't be combined with --reverse")
+	}
+
+	// The TUI explains missing logs itself; other commands reading project
+	// logs fail early with the same message
+	cmd := ctx.Command()
+	if cmd != "config path" && cmd != "doctor" && !strings.HasPrefix(cmd, "schema") && !CLI.Stdin && (cmd != "tui" || CLI.JSON) {
+		ctx.FatalIfErrorf(checkProjects())
+	}
+
+	if CLI.Here && CLI.Project == "" && !CLI.Stdin {
+		CLI.Project = currentProject()
+	}
+
+	stopProfiling, err = startProfiling(CLI.CPUProfile, CLI.MemProfile)
+	ctx.FatalIfErrorf(err)
+
 	switch ctx.Command() {
+	case "config path":
+		fmt.Println(configPath())
+	case "schema", "schema <output>":
+		if err := showSchema(CLI.Schema.Output); err != nil {
+			ctx.FatalIfErrorf(err)
+		}
+	case "summary":
+		stats.ProjectionDays = CLI.Summary.Days
+		if err := showSummary(CLI.Project); err != nil {
+			ctx.FatalIfErrorf(err)
+		}
+	case "report":
+		if err := showReport(CLI.Project, CLI.Group); err != nil {
+			ctx.FatalIfErrorf(err)
+		}
+	case "snapshot save <file>":
+		if err := saveSnapshot(CLI.Snapshot.Save.File, CLI.Project, CLI.Group); err != nil {
+			ctx.FatalIfErrorf(err)
+		}
+	case "snapshot diff <file>":
+		if err := diffSnapshot(CLI.Snapshot.Diff.File, CLI.Project, CLI.Group); err != nil {
+			ctx.FatalIfErrorf(err)
+		}
+	case "doctor":
+		if err := showDoctor(); err != nil {
+			ctx.FatalIfErrorf(err)
+		}
+	case "events":
+		if err := showEvents(CLI.Project); err != nil {
+			ctx.FatalIfErrorf(err)
+		}
 	case "projects list":
 		if err := listProjects(); err != nil {
 			ctx.FatalIfErrorf(err)
@@ -53,61 +268,461 @@ func main() {
 		if err := showStatus(); err != nil {
 			ctx.FatalIfErrorf(err)
 		}
+	case "tail":
+		if err := showTail(CLI.Project); err != nil {
+			ctx.FatalIfErrorf(err)
+		}
+	case "daily":
+		if err := showDaily(CLI.Project); err != nil {
+			ctx.FatalIfErrorf(err)
+		}
+	case "models list":
+		if err := listModels(CLI.Project); err != nil {
+			ctx.FatalIfErrorf(err)
+		}
+	case "heatmap":
+		if err := showHeatmap(CLI.Project); err != nil {
+			ctx.FatalIfErrorf(err)
+		}
 	case "tui", "":
-		if CLI.JSON {
+		if CLI.Stdin {
+			if err := showStdinUsage(CLI.Group); err != nil {
+				ctx.FatalIfErrorf(err)
+			}
+		} else if CLI.JSON {
 			if err := outputJSON(CLI.Project, CLI.Group); err != nil {
 				ctx.FatalIfErrorf(err)
 			}
 		} else {
-			p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-			if _, err := p.Run(); err != nil {
+			m := initialModel()
+			stats.ProgressFunc = sendProgress
+			p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
+			final, err := p.Run()
+			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
+				exit(1)
+			}
+			if CLI.Resume {
+				if err := saveState(final.(model).state()); err != nil {
+					fmt.Fprintf(os.Stderr, "warning: could not save TUI state: %v\n", err)
+				}
 			}
 		}
 	default:
 		// Handle unexpected commands if any
 		fmt.Printf("Unknown command: %s\n", ctx.Command())
-		os.Exit(1)
+		exit(1)
+	}
+
+	reportSkipped()
+	exit(0)
+}
+
+// parseAge parses an age in days, such as "7d", or as a Go duration such
+// as "12h"
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, errors.New("want a positive number of days, e.g. 7d")
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	return d, nil
+}
+
+// reportSkipped notes on stderr how many log lines were too long to parse
+// and, with --verbose, how many events were dropped for implausible or
+// unrecognized timestamps and how many unfinished final lines were skipped
+func reportSkipped() {
+	skipped := stats.SkippedTotals()
+	if n := skipped.Lines; n > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %d log line(s) longer than %d MB (see --max-line-size)\n", n, CLI.MaxLineSize)
+	}
+	if !CLI.Verbose {
+		return
+	}
+	if n := skipped.Timestamps; n > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d event(s) dated before 2023 or more than a day in the future\n", n)
+	}
+	if n := skipped.Undated; n > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d event(s) without a timestamp in a recognized format\n", n)
+	}
+	if n := skipped.Partial; n > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d unfinished final log line(s), which may still be being written\n", n)
 	}
 }
 
 func showStatus() error {
-	blocks, err := stats.LoadAllSessionBlocks(stats.DefaultSessionDuration)
+	if CLI.Status.Every <= 0 {
+		if len(CLI.Status.NotifyAt) > 0 {
+			return errors.New("--notify-at watches for limits, so needs --every")
+		}
+		_, err := printStatus()
+		return err
+	}
+	notifier, err := newLimitNotifier(CLI.Status.NotifyAt)
 	if err != nil {
 		return err
 	}
 
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(CLI.Status.Every)
+	defer ticker.Stop()
+
+	for {
+		// Clear the screen and move the cursor home; JSON is instead
+		// written one object per line
+		if !CLI.JSON {
+			fmt.Print("\033[H\033[2J")
+		}
+		active, err := printStatus()
+		if err != nil {
+			return err
+		}
+		notifier.check(active)
+		select {
+		case <-ticker.C:
+		case <-interrupt:
+			return nil
+		}
+	}
+}
+
+// printStatus prints the active session block and rolling usage once,
+// returning the block
+func printStatus() (*stats.SessionBlock, error) {
+	events, err := stats.LoadAllEvents()
+	if err = warnPartial(err); err != nil {
+		return nil, err
+	}
+	blocks := stats.SessionBlocksForEvents(events, CLI.SessionDuration)
+	rolling := stats.RollingUsage(events, CLI.SessionDuration, time.Now())
+
+	projection := projectCosts(stats.DailyUsageForEvents(events), time.Now())
+
 	active := stats.GetActiveBlock(blocks)
+	if CLI.JSON {
+		return active, encodeJSON(statusOutput(active, time.Now()))
+	}
 	if active == nil {
 		fmt.Println("No active session found")
-		return nil
+		printRollingUsage(rolling, CLI.Limit)
+		printMonthCost(projection)
+		printDetectedWindow(events)
+		return nil, nil
 	}
 
 	burn := stats.CalculateBurnRate(active)
 	remaining := time.Until(active.EndTime)
 
 	fmt.Printf("Session ID: %s\n", active.ID)
-	fmt.Printf("Status:     %s\n", "Active")
+	fmt.Printf("Status:     %s\n", activeStatus())
 	fmt.Printf("Start Time: %s\n", active.StartTime.Local().Format("3:04 PM MST"))
 	fmt.Printf("End Time:   %s\n", active.EndTime.Local().Format("3:04 PM MST"))
-	fmt.Printf("Duration:   %s / %s\n", time.Since(active.StartTime).Round(time.Second), stats.DefaultSessionDuration)
+	fmt.Printf("Duration:   %s / %s\n", time.Since(active.StartTime).Round(time.Second), CLI.SessionDuration)
 	fmt.Printf("Remaining:  %s\n", remaining.Round(time.Second))
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Printf("Input:      %s\n", stats.FormatTokens(active.InputTokens))
-	fmt.Printf("Output:     %s\n", stats.FormatTokens(active.OutputTokens))
-	fmt.Printf("Cache W:    %s\n", stats.FormatTokens(active.CacheCreation))
-	fmt.Printf("Cache R:    %s\n", stats.FormatTokens(active.CacheRead))
-	fmt.Printf("Total:      %s\n", stats.FormatTokens(active.TotalTokens()))
+	fmt.Printf("Input:      %s\n", formatTokens(active.InputTokens, false))
+	fmt.Printf("Output:     %s\n", formatTokens(active.OutputTokens, false))
+	if CLI.MergeCache {
+		fmt.Printf("Cache:      %s\n", formatTokens(active.CacheCreation+active.CacheRead, false))
+	} else {
+		fmt.Printf("Cache W:    %s\n", formatTokens(active.CacheCreation, false))
+		fmt.Printf("Cache R:    %s\n", formatTokens(active.CacheRead, false))
+	}
+	fmt.Printf("Total:      %s\n", formatTokens(active.TotalTokens(), false))
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	
 	if burn != nil {
 		fmt.Printf("Burn Rate:  %.1f tokens/min\n", burn.TokensPerMinute)
 	}
+	printRollingUsage(rolling, CLI.Limit)
+	printMonthCost(projection)
+	printDetectedWindow(events)
+
+	return active, nil
+}
+
+// printDetectedWindow names the window length detected from when usage
+// resumes after breaks, suggesting --session-duration when it differs
+func printDetectedWindow(events []stats.UsageEvent) {
+	d := stats.DetectSessionDuration(events)
+	if d == 0 {
+		return
+	}
+	hours := fmt.Sprintf("%gh", d.Hours())
+	if d == CLI.SessionDuration {
+		fmt.Printf("Windows:    detected %s windows\n", hours)
+		return
+	}
+	fmt.Printf("Windows:    detected %s windows; pass --session-duration %s to use them\n", hours, hours)
+}
+
+// statusOutput builds the JSON status of the active block, which is just
+// {"active": false} when there is none
+func statusOutput(active *stats.SessionBlock, now time.Time) StatusOutput {
+	if active == nil {
+		return StatusOutput{}
+	}
+
+	start, end := active.StartTime, active.EndTime
+	out := StatusOutput{
+		Active:           true,
+		ID:               active.ID,
+		Start:            &start,
+		End:              &end,
+		RemainingSeconds: int(end.Sub(now).Seconds()),
+		Tokens: &TokenCounts{
+			Input:      active.InputTokens,
+			Output:     active.OutputTokens,
+			CacheWrite: active.CacheCreation,
+			CacheRead:  active.CacheRead,
+			Total:      active.TotalTokens(),
+		},
+	}
+
+	burn := stats.CalculateBurnRate(active)
+	if burn == nil {
+		return out
+	}
+	out.BurnRate = &burn.TokensPerMinute
+
+	// With a --limit, project when the block will use it up at this rate
+	if CLI.Limit > 0 && burn.TokensPerMinute > 0 {
+		left := max(CLI.Limit-active.TotalTokens(), 0)
+		at := now.Add(time.Duration(float64(left) / burn.TokensPerMinute * float64(time.Minute)))
+		out.ProjectedExhaustion = &at
+	}
+	return out
+}
+
+// printMonthCost prints this month's cost so far and projected total
+func printMonthCost(p costProjection) {
+	fmt.Printf("This month: %s so far, %s projected\n", stats.FormatCost(p.monthToDate), stats.FormatCost(p.projected))
+}
+
+// activeStatus describes the active block, telling live sessions from
+// recently active ones when --live is set and processes can be listed
+func activeStatus() string {
+	if !CLI.Status.Live {
+		return "Active"
+	}
+	running, ok := claudeRunning()
+	switch {
+	case !ok:
+		return "Active"
+	case running:
+		return "Active (live)"
+	default:
+		return "Active (recently active)"
+	}
+}
+
+// isClaudeCommand reports whether a process's arguments are Claude Code,
+// run either directly, as claude or claude.exe, or as the package's node
+// script, e.g. node .../@anthropic-ai/claude-code/cli.js
+func isClaudeCommand(args []string) bool {
+	for i, arg := range args {
+		if i > 1 {
+			break
+		}
+		// Windows paths split on backslashes wherever this runs
+		arg = strings.ReplaceAll(arg, `\`, "/")
+		if strings.Contains(arg, "/@anthropic-ai/claude-code/") {
+			return true
+		}
+		if strings.TrimSuffix(strings.ToLower(filepath.Base(arg)), ".exe") == "claude" {
+			return true
+		}
+	}
+	return false
+}
 
+// windowLabel labels rolling usage over the session window, e.g. "Last 5h:"
+func windowLabel() string {
+	return fmt.Sprintf("Last %gh:", CLI.SessionDuration.Hours())
+}
+
+// printRollingUsage prints tokens used in the last --session-duration, as
+// a gauge against limit when one is set
+func printRollingUsage(rolling stats.TokenCounts, limit int) {
+	used := rolling.TotalTokens()
+	if limit <= 0 {
+		fmt.Printf("%-12s%s tokens\n", windowLabel(), formatTokens(used, false))
+		return
+	}
+
+	const width = 20
+	ratio := float64(used) / float64(limit)
+	filled := int(ratio * width)
+	if filled > width {
+		filled = width
+	}
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+	fmt.Printf("%-12s%s %s / %s (%.1f%%)\n", windowLabel(), bar,
+		formatTokens(used, false), formatTokens(limit, false), ratio*100)
+}
+
+// formatTokens formats a token count in the --units unit. With auto,
+// short chooses K/M/B suffixes over the full count.
+func formatTokens(n int, short bool) string {
+	switch {
+	case CLI.Units != "auto":
+		return stats.FormatTokensIn(n, CLI.Units)
+	case short:
+		return stats.FormatTokensShort(n)
+	default:
+		return stats.FormatTokens(n)
+	}
+}
+
+func showDaily(projectFilter string) error {
+	var daily []stats.DailyUsage
+	var err error
+
+	if projectFilter == "" {
+		daily, err = stats.LoadDailyUsage()
+	} else {
+		project, findErr := findProject(projectFilter)
+		if findErr != nil {
+			return findErr
+		}
+		daily, err = stats.LoadDailyUsageForProject(*project)
+	}
+	if err = warnPartial(err); err != nil {
+		return err
+	}
+
+	output := make([]UsageOutput, len(daily))
+	for i, d := range daily {
+		output[i] = usageOutput(d.AsGrouped())
+	}
+	orderPeriods(output)
+
+	if CLI.JSON {
+		return encodeJSON(output)
+	}
+
+	printUsage("Date", output)
 	return nil
 }
 
+func showStdinUsage(groupBy string) error {
+	events := stats.ParseEvents(os.Stdin, "stdin")
+
+	if levels := strings.Split(groupBy, ","); len(levels) > 1 {
+		if !CLI.JSON {
+			return fmt.Errorf("grouping by multiple levels requires --json")
+		}
+		return encodeJSON(treeOutput(stats.AggregateTree(events, levels)))
+	}
+
+	usage, hidden := filterUsage(stats.LoadGroupedUsageForEvents(events, groupBy))
+
+	output := make([]UsageOutput, len(usage))
+	for i, u := range usage {
+		output[i] = usageOutput(u)
+	}
+	orderPeriods(output)
+
+	if CLI.JSON {
+		return encodeJSON(JSONOutput{
+			Projects: []ProjectOutput{{Name: "stdin", Path: "-", Usage: output, Stats: statsOutput(usage), Hidden: hiddenOutput(hidden)}},
+		})
+	}
+
+	header := "Period"
+	switch groupBy {
+	case "session":
+		header = "Session"
+	case "role":
+		header = "Role"
+	}
+	printUsage(header, output)
+	if sum := hiddenTotal(hidden); sum != nil {
+		fmt.Printf("%s under --min-tokens: %s tokens\n", sum.Period, stats.FormatTokens(sum.TotalTokens()))
+	}
+	return nil
+}
+
+// printUsage prints a plain text usage table, one row per period and model
+func printUsage(periodHeader string, usage []UsageOutput) {
+	if len(usage) == 0 {
+		fmt.Println("No usage data found")
+		return
+	}
+
+	width := len(periodHeader)
+	modelWidth := 12 // Wider for --raw-models identifiers
+	for _, u := range usage {
+		if len(u.Period) > width {
+			width = len(u.Period)
+		}
+		for _, m := range u.Models {
+			modelWidth = max(modelWidth, len(m.Model))
+		}
+	}
+
+	fmt.Printf("%-*s  %-*s  %12s  %12s  ", width, periodHeader, modelWidth, "Model", "Input", "Output")
+	if CLI.MergeCache {
+		fmt.Printf("%14s", "Cache")
+	} else {
+		fmt.Printf("%14s  %14s", "Cache Write", "Cache Read")
+	}
+	fmt.Printf("  %14s  %10s  %10s", "Total", "Requests", "Avg/Req")
+	if CLI.Cumulative {
+		fmt.Printf("  %16s", "Cumulative")
+	}
+	if CLI.KeepZero {
+		fmt.Printf("  %6s", "Zero")
+	}
+	fmt.Println()
+	for _, u := range usage {
+		models := u.Models
+		if len(models) == 0 {
+			// --no-models leaves only the period totals
+			models = []ModelOutput{{Model: "all", Tokens: u.Totals, Requests: u.Requests}}
+		}
+		for i, m := range models {
+			period := ""
+			if i == 0 {
+				period = u.Period
+			}
+			fmt.Printf("%-*s  %-*s  %12s  %12s  ",
+				width, period,
+				modelWidth, m.Model,
+				stats.FormatTokens(m.Tokens.Input),
+				stats.FormatTokens(m.Tokens.Output),
+			)
+			if CLI.MergeCache {
+				fmt.Printf("%14s", stats.FormatTokens(m.Tokens.CacheWrite+m.Tokens.CacheRead))
+			} else {
+				fmt.Printf("%14s  %14s", stats.FormatTokens(m.Tokens.CacheWrite), stats.FormatTokens(m.Tokens.CacheRead))
+			}
+			fmt.Printf("  %14s  %10s  %10s", stats.FormatTokens(m.Tokens.Total), stats.FormatTokens(m.Requests),
+				perRequest(m.Tokens.Total, m.Requests, stats.FormatTokens))
+			if CLI.Cumulative && i == 0 {
+				fmt.Printf("  %16s", stats.FormatTokens(u.Cumulative))
+			}
+			if CLI.KeepZero && i == 0 {
+				fmt.Printf("  %6d", u.ZeroEvents)
+			}
+			fmt.Println()
+		}
+	}
+}
+
 func listProjects() error {
 	projects, err := stats.ListProjects()
 	if err != nil {
@@ -121,123 +736,572 @@ func listProjects() error {
 }
 
 // JSON output types
+
+// schemaVersion is the version of the JSONOutput shape. Bump it when a
+// field is renamed or removed or changes meaning, so consumers can tell.
+const schemaVersion = 1
+
 type JSONOutput struct {
-	Projects []ProjectOutput `json:"projects"`
+	SchemaVersion int             `json:"schema_version"`
+	Version       string          `json:"version"` // claudette version that wrote it
+	GeneratedAt   time.Time       `json:"generated_at"`
+	Projects      []ProjectOutput `json:"projects"`
 }
 
 type ProjectOutput struct {
-	Name  string        `json:"name"`
-	Path  string        `json:"path"`
-	Usage []UsageOutput `json:"usage"`
+	Name   string        `json:"name"`
+	Path   string        `json:"path"`
+	Usage  []UsageOutput `json:"usage"`
+	Stats  *StatsOutput  `json:"stats,omitempty"`
+	Hidden *HiddenOutput `json:"hidden,omitempty"` // Periods left out by --min-tokens
+}
+
+// HiddenOutput sums the periods --min-tokens left out of Usage, so totals
+// can still account for them. It's omitted with --exclude-filtered.
+type HiddenOutput struct {
+	Periods int         `json:"periods"`
+	Totals  TokenCounts `json:"totals"`
+}
+
+// StatsOutput describes how totals vary across the periods in Usage
+type StatsOutput struct {
+	Max       int    `json:"max"`
+	MaxPeriod string `json:"max_period"`
+	Min       int    `json:"min"`
+	MinPeriod string `json:"min_period"`
+	Avg       int    `json:"avg"`
+	Median    int    `json:"median"`
 }
 
 type UsageOutput struct {
-	Period string        `json:"period"`
-	Models []ModelOutput `json:"models"`
-	Totals TokenCounts   `json:"totals"`
+	Period     string        `json:"period"`
+	Models     []ModelOutput `json:"models,omitempty"` // Omitted with --no-models
+	Totals     TokenCounts   `json:"totals"`
+	Requests   int           `json:"requests"`                    // Usage records, roughly one per API request
+	Cumulative int           `json:"cumulative,omitempty"`        // Tokens through this period, with --cumulative
+	ZeroEvents int           `json:"zero_token_events,omitempty"` // Records without tokens, with --keep-zero
+}
+
+// TreeOutput is the JSON shape for multi-level grouping
+type TreeOutput struct {
+	Groups []NodeOutput `json:"groups"`
+}
+
+type NodeOutput struct {
+	Key      string       `json:"key"`
+	Totals   TokenCounts  `json:"totals"`
+	Requests int          `json:"requests"`
+	Children []NodeOutput `json:"children,omitempty"`
 }
 
 type ModelOutput struct {
-	Model  string      `json:"model"`
-	Tokens TokenCounts `json:"tokens"`
+	Model    string      `json:"model"`
+	Tokens   TokenCounts `json:"tokens"`
+	Requests int         `json:"requests"`
+}
+
+// SessionOutput is the JSON export of a single session block
+type SessionOutput struct {
+	ID             string        `json:"id"`
+	Start          time.Time     `json:"start"`
+	End            time.Time     `json:"end"`
+	LastEvent      time.Time     `json:"last_event"`
+	Active         bool          `json:"active"`
+	Models         []string      `json:"models"`
+	Totals         TokenCounts   `json:"totals"`
+	Cost           float64       `json:"cost_usd"`
+	BurnRate       *float64      `json:"tokens_per_minute,omitempty"`
+	CacheReadShare float64       `json:"cache_read_share"` // Fraction of all tokens that were cache reads
+	Usage          []UsageOutput `json:"usage"`
+	Events         []EventOutput `json:"events"`
+}
+
+// StatusOutput is the JSON shape for status --json. Only Active is set
+// when no session is active.
+type StatusOutput struct {
+	Active              bool         `json:"active"`
+	ID                  string       `json:"id,omitempty"`
+	Start               *time.Time   `json:"start,omitempty"`
+	End                 *time.Time   `json:"end,omitempty"`
+	RemainingSeconds    int          `json:"remaining_seconds,omitempty"`
+	Tokens              *TokenCounts `json:"tokens,omitempty"`
+	BurnRate            *float64     `json:"tokens_per_minute,omitempty"`
+	ProjectedExhaustion *time.Time   `json:"projected_exhaustion,omitempty"` // When --limit is reached at the current burn rate
+}
+
+type EventOutput struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Model     string      `json:"model"`
+	Project   string      `json:"project"`
+	SessionID string      `json:"session_id,omitempty"`
+	Tokens    TokenCounts `json:"tokens"`
 }
 
 type TokenCounts struct {
-	Input      int `json:"input"`
-	Output     int `json:"output"`
-	CacheWrite int `json:"cache_write"`
-	CacheRead  int `json:"cache_read"`
-	Total      int `json:"total"`
+	Input        int `json:"input"`
+	Output       int `json:"output"`
+	CacheWrite   int `json:"cache_write"`
+	CacheWrite1h int `json:"cache_write_1h,omitempty"` // Portion of cache_write to the 1-hour cache
+	CacheRead    int `json:"cache_read"`
+	Total        int `json:"total"`
+}
+
+// MergedTokenCounts is how TokenCounts is written with --merge-cache, with
+// cache writes and reads as one count
+type MergedTokenCounts struct {
+	Input  int `json:"input"`
+	Output int `json:"output"`
+	Cache  int `json:"cache"`
+	Total  int `json:"total"`
+}
+
+func (t TokenCounts) MarshalJSON() ([]byte, error) {
+	if CLI.MergeCache {
+		return json.Marshal(MergedTokenCounts{
+			Input:  t.Input,
+			Output: t.Output,
+			Cache:  t.CacheWrite + t.CacheRead,
+			Total:  t.Total,
+		})
+	}
+	type plain TokenCounts // Without this method, to avoid recursing
+	return json.Marshal(plain(t))
 }
 
 func outputJSON(projectFilter, groupBy string) error {
-	projects, err := stats.ListProjects()
+	if levels := strings.Split(groupBy, ","); len(levels) > 1 {
+		return outputTreeJSON(projectFilter, levels)
+	}
+
+	output, err := usageJSON(projectFilter, groupBy)
 	if err != nil {
 		return err
 	}
+	return encodeJSON(output)
+}
+
+// usageJSON builds the usage of every project, or just projectFilter, by
+// period
+func usageJSON(projectFilter, groupBy string) (JSONOutput, error) {
+	projects, err := stats.ListProjects()
+	if err != nil {
+		return JSONOutput{}, err
+	}
 
 	// Filter to specific project if requested
 	if projectFilter != "" {
-		var found *stats.Project
-		for _, p := range projects {
-			if p.Name == projectFilter {
-				found = &p
-				break
-			}
-		}
-		if found == nil {
-			return fmt.Errorf("project not found: %s", projectFilter)
+		found, err := findProject(projectFilter)
+		if err != nil {
+			return JSONOutput{}, err
 		}
 		projects = []stats.Project{*found}
 	}
 
 	output := JSONOutput{
-		Projects: make([]ProjectOutput, len(projects)),
+		SchemaVersion: schemaVersion,
+		Version:       version,
+		GeneratedAt:   time.Now(),
+		Projects:      []ProjectOutput{},
 	}
 
-	for i, p := range projects {
-		usage, err := stats.LoadGroupedUsageForProject(p.Path, groupBy)
-		if err != nil {
-			return err
+	for _, p := range projects {
+		events, err := stats.LoadProjectEvents(p)
+		if err = warnPartial(err); err != nil {
+			return JSONOutput{}, err
 		}
 
-		proj := ProjectOutput{
-			Name:  p.Name,
-			Path:  p.Path,
-			Usage: make([]UsageOutput, len(usage)),
-		}
-
-		for j, u := range usage {
-			out := UsageOutput{
-				Period: u.Period,
-				Models: make([]ModelOutput, len(u.Models)),
-				Totals: TokenCounts{
-					Input:      u.InputTotal,
-					Output:     u.OutputTotal,
-					CacheWrite: u.CacheCreateTotal,
-					CacheRead:  u.CacheReadTotal,
-					Total:      u.InputTotal + u.OutputTotal + u.CacheCreateTotal + u.CacheReadTotal,
-				},
-			}
-
-			for k, modelName := range u.Models {
-				m := u.ByModel[modelName]
-				out.Models[k] = ModelOutput{
-					Model: modelName,
-					Tokens: TokenCounts{
-						Input:      m.Input,
-						Output:     m.Output,
-						CacheWrite: m.CacheCreate,
-						CacheRead:  m.CacheRead,
-						Total:      m.Input + m.Output + m.CacheCreate + m.CacheRead,
-					},
-				}
+		if !CLI.PerFile {
+			output.Projects = append(output.Projects, projectOutput(p.Name, p.Path, events, groupBy))
+			continue
+		}
+
+		// With --per-file each event's project is its log file
+		byFile := make(map[string][]stats.UsageEvent)
+		var files []string
+		for _, e := range events {
+			if _, ok := byFile[e.Project]; !ok {
+				files = append(files, e.Project)
 			}
+			byFile[e.Project] = append(byFile[e.Project], e)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			output.Projects = append(output.Projects, projectOutput(file, p.Path, byFile[file], groupBy))
+		}
+	}
+
+	return output, nil
+}
+
+// projectOutput builds the JSON representation of one project's usage
+func projectOutput(name, path string, events []stats.UsageEvent, groupBy string) ProjectOutput {
+	usage, hidden := filterUsage(stats.LoadGroupedUsageForEvents(events, groupBy))
+
+	proj := ProjectOutput{
+		Name:   name,
+		Path:   path,
+		Usage:  make([]UsageOutput, len(usage)),
+		Stats:  statsOutput(usage),
+		Hidden: hiddenOutput(hidden),
+	}
+	for i, u := range usage {
+		proj.Usage[i] = usageOutput(u)
+	}
+	orderPeriods(proj.Usage)
+	return proj
+}
+
+// filterUsage drops periods under --min-tokens, returning those kept and
+// those dropped, which totals still count unless --exclude-filtered is set
+func filterUsage(usage []stats.GroupedUsage) (kept, hidden []stats.GroupedUsage) {
+	kept, hidden = stats.SplitMinTokens(usage, CLI.MinTokens)
+	if CLI.ExcludeFiltered {
+		return kept, nil
+	}
+	return kept, hidden
+}
 
-			proj.Usage[j] = out
+// hiddenTotal sums the periods hidden by --min-tokens under a label like
+// "3 hidden", or returns nil when there are none
+func hiddenTotal(hidden []stats.GroupedUsage) *stats.GroupedUsage {
+	if len(hidden) == 0 {
+		return nil
+	}
+	sum := stats.SumUsage(hidden)
+	sum.Period = fmt.Sprintf("%d hidden", len(hidden))
+	return &sum
+}
+
+// hiddenOutput builds the JSON summary of periods hidden by --min-tokens
+func hiddenOutput(hidden []stats.GroupedUsage) *HiddenOutput {
+	sum := hiddenTotal(hidden)
+	if sum == nil {
+		return nil
+	}
+	return &HiddenOutput{Periods: len(hidden), Totals: usageOutput(*sum).Totals}
+}
+
+// orderPeriods fills in running totals when --cumulative is set and puts
+// the newest period first when --reverse is set; periods are otherwise
+// oldest first
+func orderPeriods(usage []UsageOutput) {
+	if CLI.Cumulative {
+		running := 0
+		for i := range usage {
+			running += usage[i].Totals.Total
+			usage[i].Cumulative = running
 		}
+	}
+	if CLI.Reverse {
+		slices.Reverse(usage)
+	}
+}
 
-		output.Projects[i] = proj
+// encodeJSON writes v to stdout, indented unless --compact is set
+func encodeJSON(v interface{}) error {
+	return writeJSON(os.Stdout, v)
+}
+
+// writeJSON writes v to w, indented unless --compact is set
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	if !CLI.Compact {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
+
+// outputTreeJSON prints usage nested by several grouping levels
+func outputTreeJSON(projectFilter string, levels []string) error {
+	var events []stats.UsageEvent
+	var err error
+
+	if projectFilter == "" {
+		events, err = stats.LoadAllEvents()
+	} else {
+		project, findErr := findProject(projectFilter)
+		if findErr != nil {
+			return findErr
+		}
+		events, err = stats.LoadProjectEvents(*project)
+	}
+	if err = warnPartial(err); err != nil {
+		return err
 	}
 
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetIndent("", "  ")
-	return enc.Encode(output)
+	return encodeJSON(treeOutput(stats.AggregateTree(events, levels)))
 }
 
-// TUI code below
+func treeOutput(nodes []*stats.UsageNode) TreeOutput {
+	return TreeOutput{Groups: nodeOutputs(nodes)}
+}
 
-var (
-	appStyle = lipgloss.NewStyle().Padding(1, 2)
+func nodeOutputs(nodes []*stats.UsageNode) []NodeOutput {
+	if len(nodes) == 0 {
+		return nil
+	}
+	out := make([]NodeOutput, len(nodes))
+	for i, n := range nodes {
+		out[i] = NodeOutput{
+			Key: n.Key,
+			Totals: TokenCounts{
+				Input:      n.Input,
+				Output:     n.Output,
+				CacheWrite: n.CacheCreate,
+				CacheRead:  n.CacheRead,
+				Total:      n.TotalTokens(),
+			},
+			Requests: n.Requests,
+			Children: nodeOutputs(n.Children),
+		}
+	}
+	return out
+}
 
-	titleStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FFFDF5")).
-			Background(lipgloss.Color("#7C3AED")).
-			Padding(0, 1)
+// periodGroups are the time periods usage can be grouped by
+var periodGroups = []string{"minute", "hour", "day", "week", "month", "quarter", "year", "weekday", "all"}
 
-	helpStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#626262"))
-)
+// isPeriodGroup reports whether groupBy is a single time period
+func isPeriodGroup(groupBy string) bool {
+	if _, ok := stats.BucketDuration(groupBy); ok {
+		return true
+	}
+	for _, p := range periodGroups {
+		if groupBy == p {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPeriodGroups() string {
+	return strings.Join(periodGroups, ", ")
+}
+
+// validateGroup checks a --group value. A single level must be a time
+// period, "session" or "role"; nested levels may also be "project" or "model".
+func validateGroup(groupBy string) error {
+	levels := strings.Split(groupBy, ",")
+	seen := make(map[string]bool)
+	for _, level := range levels {
+		valid := level == "session" || level == "role" || isPeriodGroup(level)
+		if len(levels) > 1 && (level == "project" || level == "model") {
+			valid = true
+		}
+		if !valid {
+			return fmt.Errorf("invalid group %q: expected one of %s, session, role, or a duration from 1m to 24h such as 15m", level, joinPeriodGroups())
+		}
+		if seen[level] {
+			return fmt.Errorf("group level %q given more than once", level)
+		}
+		seen[level] = true
+	}
+	return nil
+}
+
+// warnPartial reports projects that failed to parse on stderr and returns
+// nil, so incomplete results are still shown; other errors pass through
+func warnPartial(err error) error {
+	var partial *stats.PartialError
+	if !errors.As(err, &partial) {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "warning: %v, totals are incomplete\n", partial)
+	for _, p := range partial.Projects {
+		fmt.Fprintf(os.Stderr, "  %v\n", p)
+	}
+	return nil
+}
+
+// tildePath abbreviates the home directory in path as ~
+func tildePath(path string) string {
+	home := os.Getenv("HOME")
+	if home != "" && strings.HasPrefix(path, home+string(filepath.Separator)) {
+		return "~" + strings.TrimPrefix(path, home)
+	}
+	return path
+}
+
+// errNoProjects explains where claudette looked when it found no projects
+func errNoProjects() error {
+	if len(CLI.ExcludeProject) > 0 {
+		return fmt.Errorf("no projects left after --exclude-project %s", strings.Join(CLI.ExcludeProject, ", "))
+	}
+
+	var roots []string
+	for _, root := range stats.SearchRoots() {
+		roots = append(roots, tildePath(root))
+	}
+	return fmt.Errorf("no Claude Code logs found under %s — is Claude Code installed?", strings.Join(roots, ", "))
+}
+
+// checkProjects returns errNoProjects when there are no projects to read
+func checkProjects() error {
+	projects, err := stats.ListProjects()
+	if err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		return errNoProjects()
+	}
+	return nil
+}
+
+// currentProject returns the name of the project for the working
+// directory, or "" when there isn't one
+func currentProject() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	projects, err := stats.ListProjects()
+	if err != nil {
+		return ""
+	}
+	project, ok := stats.ProjectForDir(projects, dir)
+	if !ok {
+		return ""
+	}
+	return project.Name
+}
+
+// findProject looks up a project by its display name
+func findProject(name string) (*stats.Project, error) {
+	projects, err := stats.ListProjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		if p.Name == name {
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("project not found: %s", name)
+}
+
+// eventOutput builds the JSON representation of a single usage event
+func eventOutput(e stats.UsageEvent) EventOutput {
+	return EventOutput{
+		Timestamp: e.Timestamp,
+		Model:     e.Model,
+		Project:   e.Project,
+		SessionID: e.SessionID,
+		Tokens: TokenCounts{
+			Input:        e.InputTokens,
+			Output:       e.OutputTokens,
+			CacheWrite:   e.CacheCreation,
+			CacheWrite1h: e.CacheCreation1h,
+			CacheRead:    e.CacheRead,
+			Total:        e.TotalTokens(),
+		},
+	}
+}
+
+// sessionOutput builds the JSON representation of a session block,
+// with its usage broken down by model and every event it contains
+func sessionOutput(block stats.SessionBlock) SessionOutput {
+	out := SessionOutput{
+		ID:             block.ID,
+		Start:          block.StartTime,
+		End:            block.EndTime,
+		LastEvent:      block.ActualEndTime,
+		Active:         block.IsActive,
+		Models:         block.Models,
+		Cost:           pricing.EventsCost(block.Entries),
+		CacheReadShare: block.CacheReadShare(),
+		Usage:          []UsageOutput{},
+		Events:         make([]EventOutput, len(block.Entries)),
+	}
+
+	if burn := stats.CalculateBurnRate(&block); burn != nil {
+		out.BurnRate = &burn.TokensPerMinute
+	}
+
+	usage := stats.LoadGroupedUsageForEvents(block.Entries, "model")
+	for _, u := range usage {
+		out.Usage = append(out.Usage, periodOutput(u))
+	}
+	out.Totals = periodOutput(stats.SumUsage(usage)).Totals
+
+	for i, e := range block.Entries {
+		out.Events[i] = eventOutput(e)
+	}
+
+	return out
+}
+
+// statsOutput builds the JSON period statistics, nil when there is no usage
+func statsOutput(usage []stats.GroupedUsage) *StatsOutput {
+	if len(usage) == 0 {
+		return nil
+	}
+	s := stats.PeriodStats(usage)
+	return &StatsOutput{
+		Max:       s.Max,
+		MaxPeriod: s.MaxPeriod,
+		Min:       s.Min,
+		MinPeriod: s.MinPeriod,
+		Avg:       s.Avg,
+		Median:    s.Median,
+	}
+}
+
+// usageOutput builds the JSON representation of a single period for the
+// command line, without its models when --no-models is set
+func usageOutput(u stats.GroupedUsage) UsageOutput {
+	out := periodOutput(u)
+	if CLI.NoModels {
+		out.Models = nil
+	}
+	return out
+}
+
+// periodOutput builds the JSON representation of a single period with its
+// per-model breakdown
+func periodOutput(u stats.GroupedUsage) UsageOutput {
+	out := UsageOutput{
+		Period: u.Period,
+		Totals: TokenCounts{
+			Input:        u.InputTotal,
+			Output:       u.OutputTotal,
+			CacheWrite:   u.CacheCreateTotal,
+			CacheWrite1h: u.CacheCreate1h,
+			CacheRead:    u.CacheReadTotal,
+			Total:        u.TotalTokens(),
+		},
+		Requests:   u.Requests,
+		ZeroEvents: u.ZeroEvents,
+	}
+	out.Models = make([]ModelOutput, len(u.Models))
+
+	for k, modelName := range u.Models {
+		m := u.ByModel[modelName]
+		out.Models[k] = ModelOutput{
+			Model: modelName,
+			Tokens: TokenCounts{
+				Input:        m.Input,
+				Output:       m.Output,
+				CacheWrite:   m.CacheCreate,
+				CacheWrite1h: m.CacheCreate1h,
+				CacheRead:    m.CacheRead,
+				Total:        m.Input + m.Output + m.CacheCreate + m.CacheRead,
+			},
+			Requests: m.Requests,
+		}
+	}
+
+	return out
+}
+
+// TUI code below
+
+// narrowWidth is the terminal width below which tables switch to short
+// K/M/B numbers and the session panel collapses to one line
+const narrowWidth = 100
+
+// stackedWidth is the terminal width below which tables are drawn as
+// stacked blocks of fields, as no table of theirs fits
+const stackedWidth = 60
 
 type view int
 
@@ -246,51 +1310,224 @@ const (
 	usageTableView
 	sessionListView
 	sessionUsageTableView
+	compareView
+	helpView
 )
 
 type model struct {
-	list        list.Model
-	listReady   bool
-	currentView view
-	selected    string
-	usage       []stats.GroupedUsage
-	sessions    []stats.SessionBlock
-	groupBy     string // "model" or "project"
-	width       int
-	height      int
-	err         error
+	list          list.Model
+	listReady     bool
+	currentView   view
+	prevView      view // View to return to when leaving helpView
+	selected      string
+	usage         []stats.GroupedUsage
+	sessions      []stats.SessionBlock
+	session       *stats.SessionBlock // Session shown in sessionUsageTableView
+	groupBy       string              // "model" or "project"
+	relativeTimes bool                // Session list shows relative times
+	dense         bool                // Tables drop row borders and padding
+	allTime       *stats.GroupedUsage // All-time totals for the usage table
+	hidden        *stats.GroupedUsage // Periods under --min-tokens, counted in the Total row
+	marked        map[string]bool     // Project paths marked for comparison
+	compared      [2]string           // Names of the projects in compareView
+	compare       [2][]stats.GroupedUsage
+	labels        sessionLabels
+	labeling      bool // The label input is open on the selected session
+	labelInput    textinput.Model
+	notice        string
+	quitPending   bool                // q was pressed once with --confirm-quit
+	resume        *tuiState           // Selection to restore with --resume
+	warning       string              // Shown while the loaded data is incomplete
+	active        *stats.SessionBlock // Active block for the --limit gauge
+	activeLoaded  bool
+	loading       bool
+	progress      stats.Progress
+	progressCh    chan stats.Progress
+	spinner       spinner.Model
+	viewport      viewport.Model // Scrolls tables taller than the terminal
+	width         int
+	height        int
+	err           error
 }
 
 type projectItem struct {
-	name string
-	path string
+	name       string
+	path       string
 	actualPath string
+	merged     []string // Further log directories from the same working directory
+	tokens     int
+	cost       float64
+}
+
+// project returns the project to load usage from, whose path is empty
+// for All Projects
+func (i projectItem) project() stats.Project {
+	return stats.Project{Name: i.name, Path: i.path, ActualPath: i.actualPath, Merged: i.merged}
+}
+
+func (i projectItem) Title() string { return i.name }
+
+func (i projectItem) Description() string {
+	if i.tokens == 0 {
+		return "No usage"
+	}
+	return fmt.Sprintf("%s tokens • %s", stats.FormatTokens(i.tokens), stats.FormatCost(i.cost))
+}
+
+// FilterValue lets the list's fuzzy filter match on the project path as
+// well as its name. The title comes first so that highlighted matches line
+// up with it.
+func (i projectItem) FilterValue() string { return i.name + " " + i.actualPath }
+
+type projectsLoadedMsg struct {
+	projects []projectItem
+	all      projectItem // Totals across every project, deduplicated
+	warning  string      // Set when some projects failed to parse
+}
+
+type usageLoadedMsg struct {
+	usage   []stats.GroupedUsage
+	hidden  *stats.GroupedUsage // Periods under --min-tokens, still totalled
+	allTime *stats.GroupedUsage // Totals before any filtering, when known
+	warning string              // Set when some projects failed to parse
+	err     error
+}
+
+type errMsg struct{ err error }
+
+// progressMsg reports parsing progress during the load sending on ch
+type progressMsg struct {
+	progress stats.Progress
+	ch       chan stats.Progress
+}
+
+// partialWarning describes a partial load error for display, or returns ""
+func partialWarning(err error) string {
+	if err == nil {
+		return ""
+	}
+	return fmt.Sprintf("⚠ %v, totals are incomplete", err)
+}
+
+// waitForProgress waits for the next progress update of a load, ending
+// once the load is done and its channel closed
+func waitForProgress(ch chan stats.Progress) tea.Cmd {
+	return func() tea.Msg {
+		p, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return progressMsg{progress: p, ch: ch}
+	}
+}
+
+// loadProgress holds the channel of the load whose progress is shown
+var loadProgress struct {
+	sync.Mutex
+	ch chan stats.Progress
+}
+
+// sendProgress is the TUI's stats.ProgressFunc
+func sendProgress(p stats.Progress) {
+	loadProgress.Lock()
+	defer loadProgress.Unlock()
+	// Drop updates the TUI hasn't caught up with yet
+	select {
+	case loadProgress.ch <- p:
+	default:
+	}
+}
+
+// withProgress runs load with its progress sent on ch, closing ch when
+// load returns
+func withProgress(load tea.Cmd, ch chan stats.Progress) tea.Cmd {
+	return func() tea.Msg {
+		loadProgress.Lock()
+		loadProgress.ch = ch
+		loadProgress.Unlock()
+		defer func() {
+			loadProgress.Lock()
+			defer loadProgress.Unlock()
+			if loadProgress.ch == ch {
+				loadProgress.ch = nil
+			}
+			close(ch)
+		}()
+		return load()
+	}
+}
+
+// noticeMsg is a transient message shown in the help line
+type noticeMsg string
+
+type clearNoticeMsg struct{}
+
+func initialModel() model {
+	m := model{
+		currentView:   usageListView,
+		groupBy:       "model",
+		relativeTimes: true,
+		dense:         CLI.Dense,
+		marked:        make(map[string]bool),
+		labels:        loadLabels(),
+		loading:       true,
+		progressCh:    make(chan stats.Progress, 1),
+		spinner:       spinner.New(spinner.WithSpinner(spinner.Dot)),
+		viewport:      viewport.New(0, 0),
+	}
+	// Keep u and d for switching views and dense rows, and ← for going back
+	m.viewport.KeyMap.HalfPageUp.SetEnabled(false)
+	m.viewport.KeyMap.HalfPageDown.SetEnabled(false)
+	m.viewport.KeyMap.Left.SetEnabled(false)
+	m.viewport.KeyMap.Right.SetEnabled(false)
+
+	if CLI.Resume {
+		if state := loadState(); state != nil {
+			m.resume = state
+			if state.View == "sessions" {
+				m.currentView = sessionListView
+			}
+			if state.GroupBy == "project" {
+				m.groupBy = state.GroupBy
+			}
+		}
+	}
+	if CLI.Here && CLI.Project != "" {
+		m.currentView = usageListView
+		m.resume = &tuiState{View: "usage", Item: CLI.Project, Open: true}
+	}
+	return m
 }
 
-func (i projectItem) Title() string       { return i.name }
-func (i projectItem) Description() string { return i.actualPath }
-func (i projectItem) FilterValue() string { return i.name }
-
-type projectsLoadedMsg struct {
-	projects []stats.Project
+func (m model) Init() tea.Cmd {
+	load := loadUsageList
+	if m.currentView == sessionListView {
+		load = loadSessions
+	}
+	cmds := []tea.Cmd{withProgress(load, m.progressCh), waitForProgress(m.progressCh), m.spinner.Tick}
+	if CLI.Limit > 0 {
+		cmds = append(cmds, loadActiveBlock)
+	}
+	return tea.Batch(cmds...)
 }
 
-type usageLoadedMsg struct {
-	usage []stats.GroupedUsage
-	err   error
+// gaugeRefresh is how often the --limit gauge reloads the active block
+const gaugeRefresh = time.Minute
+
+// activeBlockMsg carries the active session block for the --limit gauge,
+// nil when there is none
+type activeBlockMsg struct {
+	block *stats.SessionBlock
 }
 
-type errMsg struct{ err error }
+type gaugeTickMsg struct{}
 
-func initialModel() model {
-	return model{
-		currentView: usageListView,
-		groupBy:     "model",
+func loadActiveBlock() tea.Msg {
+	blocks, err := stats.LoadAllSessionBlocks(CLI.SessionDuration)
+	if err != nil && !stats.IsPartial(err) {
+		return activeBlockMsg{}
 	}
-}
-
-func (m model) Init() tea.Cmd {
-	return loadUsageList
+	return activeBlockMsg{block: stats.GetActiveBlock(blocks)}
 }
 
 func loadUsageList() tea.Msg {
@@ -298,82 +1535,204 @@ func loadUsageList() tea.Msg {
 	if err != nil {
 		return errMsg{err}
 	}
-	return projectsLoadedMsg{projects}
+	if len(projects) == 0 {
+		return errMsg{errNoProjects()}
+	}
+
+	// Total each project up front so the list can show it without
+	// drilling in, from a single pass over every log. Each row matches the
+	// project's own view, logs copied between projects are counted once in
+	// All Projects, and unreadable projects still list with what was read.
+	events, byProject, err := stats.LoadEventsByProject()
+	if err != nil && !stats.IsPartial(err) {
+		return errMsg{err}
+	}
+
+	var items []projectItem
+	for _, p := range projects {
+		projectEvents := byProject[p.Name]
+		if len(projectEvents) == 0 && !CLI.IncludeEmpty {
+			continue
+		}
+		items = append(items, projectItem{
+			name:       p.Name,
+			path:       p.Path,
+			actualPath: p.ActualPath,
+			merged:     p.Merged,
+			tokens:     eventTokens(projectEvents),
+			cost:       pricing.EventsCost(projectEvents),
+		})
+	}
+	all := projectItem{
+		name:       "All Projects",
+		actualPath: "Aggregate usage across all projects",
+		tokens:     eventTokens(events),
+		cost:       pricing.EventsCost(events),
+	}
+
+	return projectsLoadedMsg{projects: items, all: all, warning: partialWarning(err)}
+}
+
+// eventTokens sums the tokens of every event
+func eventTokens(events []stats.UsageEvent) int {
+	total := 0
+	for _, e := range events {
+		total += e.TotalTokens()
+	}
+	return total
 }
 
-func loadUsage(projectPath string) tea.Cmd {
+func loadUsage(project stats.Project) tea.Cmd {
 	return func() tea.Msg {
 		var usage []stats.GroupedUsage
 		var err error
 
-		if projectPath == "" {
-			usage, err = stats.LoadGroupedUsage("day")
+		// The table lists periods, so other groupings keep the default
+		period := "day"
+		if isPeriodGroup(CLI.Group) {
+			period = CLI.Group
+		}
+		if project.Path == "" {
+			usage, err = stats.LoadGroupedUsage(period)
 		} else {
-			usage, err = stats.LoadGroupedUsageForProject(projectPath, "day")
+			usage, err = stats.LoadGroupedUsageForProject(project, period)
+		}
+		if err != nil && !stats.IsPartial(err) {
+			return usageLoadedMsg{err: err}
 		}
 
-		return usageLoadedMsg{usage, err}
+		if CLI.ExcludeFiltered {
+			usage, _ = stats.SplitMinTokens(usage, CLI.MinTokens)
+		}
+		allTime := stats.SumUsage(usage)
+		usage, hidden := filterUsage(usage)
+		if CLI.Reverse {
+			slices.Reverse(usage)
+		}
+		return usageLoadedMsg{usage: usage, hidden: hiddenTotal(hidden), allTime: &allTime, warning: partialWarning(err)}
 	}
 }
 
 func loadSessionUsage(block stats.SessionBlock, groupBy string) tea.Cmd {
 	return func() tea.Msg {
-		usage := stats.LoadGroupedUsageForEvents(block.Entries, groupBy)
-		return usageLoadedMsg{usage, nil}
+		usage, hidden := filterUsage(stats.LoadGroupedUsageForEvents(block.Entries, groupBy))
+		if CLI.Reverse {
+			slices.Reverse(usage)
+		}
+		return usageLoadedMsg{usage: usage, hidden: hiddenTotal(hidden)}
 	}
 }
 
 func loadSessions() tea.Msg {
-	sessions, err := stats.LoadAllSessionBlocks(stats.DefaultSessionDuration)
-	if err != nil {
+	sessions, err := stats.LoadAllSessionBlocks(CLI.SessionDuration)
+	if err != nil && !stats.IsPartial(err) {
 		return errMsg{err}
 	}
+	sessions = stats.DropSmallSessions(sessions, CLI.MinTokens)
 	// Sort sessions newest first
 	sort.Slice(sessions, func(i, j int) bool {
 		return sessions[i].StartTime.After(sessions[j].StartTime)
 	})
-	return sessionsLoadedMsg{sessions}
+	return sessionsLoadedMsg{sessions: sessions, warning: partialWarning(err)}
 }
 
 type sessionItem struct {
-	block stats.SessionBlock
+	block    stats.SessionBlock
+	relative bool   // Show "2 hours ago" style times in the title
+	label    string // Given with the l key, shown after the time
 }
 
 func (i sessionItem) Title() string {
 	if i.block.IsGap {
 		return fmt.Sprintf("Gap: %s", stats.FormatDuration(i.block.EndTime.Sub(i.block.StartTime)))
 	}
-	activeStr := ""
-	if i.block.IsActive {
-		activeStr = " (Active)"
+	title := "Session: " + i.timeRange()
+	if i.relative {
+		if i.block.IsActive {
+			title = "Session: ongoing (Active)"
+		} else {
+			title = fmt.Sprintf("Session: %s", stats.FormatRelative(i.block.ActualEndTime, time.Now()))
+		}
 	}
-	start := i.block.StartTime.Local().Format("Jan 02, 3:04 PM")
-	end := i.block.EndTime.Local().Format("3:04 PM MST")
-	return fmt.Sprintf("Session: %s - %s%s", start, end, activeStr)
+	if i.label != "" {
+		title += " • " + i.label
+	}
+	return title
 }
 
 func (i sessionItem) Description() string {
 	if i.block.IsGap {
 		return fmt.Sprintf("%s to %s", i.block.StartTime.Local().Format("3:04 PM"), i.block.EndTime.Local().Format("3:04 PM MST"))
 	}
-	return fmt.Sprintf("Tokens: %s | Models: %s",
+	desc := fmt.Sprintf("Tokens: %s | Models: %s",
 		stats.FormatTokens(i.block.TotalTokens()),
 		fmt.Sprintf("%v", i.block.Models),
 	)
+	if i.relative {
+		desc = i.timeRange() + " | " + desc
+	}
+	return desc
+}
+
+// timeRange formats the block's absolute start and end times
+func (i sessionItem) timeRange() string {
+	activeStr := ""
+	if i.block.IsActive {
+		activeStr = " (Active)"
+	}
+	start := i.block.StartTime.Local().Format("Jan 02, 3:04 PM")
+	end := i.block.EndTime.Local().Format("3:04 PM MST")
+	return fmt.Sprintf("%s - %s%s", start, end, activeStr)
 }
 
-func (i sessionItem) FilterValue() string { return i.Title() }
+// FilterValue lets the fuzzy filter match absolute times and models too,
+// keeping the title first so highlighted matches line up with it
+func (i sessionItem) FilterValue() string {
+	return i.Title() + " " + i.timeRange() + " " + strings.Join(i.block.Models, " ")
+}
 
 type sessionsLoadedMsg struct {
 	sessions []stats.SessionBlock
+	warning  string
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.labeling {
+			return m, m.updateLabeling(msg)
+		}
+
+		// With --confirm-quit a first q only asks; any other key cancels
+		filtering := m.listReady && m.list.FilterState() == list.Filtering
+		if CLI.ConfirmQuit && msg.String() == "q" && !filtering && !m.quitPending {
+			m.quitPending = true
+			m.notice = "Press q again to quit"
+			return m, nil
+		}
+		if m.quitPending && msg.String() != "q" {
+			m.quitPending = false
+			m.notice = ""
+		}
+
+		if m.currentView == helpView {
+			switch {
+			case key.Matches(msg, key.NewBinding(key.WithKeys("q", "ctrl+c"))):
+				return m, tea.Quit
+			case key.Matches(msg, key.NewBinding(key.WithKeys("?", "esc"))):
+				m.currentView = m.prevView
+			}
+			return m, nil
+		}
 		switch {
 		case key.Matches(msg, key.NewBinding(key.WithKeys("q", "ctrl+c"))):
 			return m, tea.Quit
+		case key.Matches(msg, key.NewBinding(key.WithKeys("?"))):
+			if !m.listReady || m.list.FilterState() != list.Filtering {
+				m.prevView = m.currentView
+				m.currentView = helpView
+				return m, nil
+			}
 		case key.Matches(msg, key.NewBinding(key.WithKeys("g"))):
 			if m.currentView == sessionUsageTableView {
 				if m.groupBy == "model" {
@@ -382,17 +1741,44 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.groupBy = "model"
 				}
 				// Reload current session with new grouping
-				for _, s := range m.sessions {
-					title := fmt.Sprintf("Session: %s - %s", s.StartTime.Local().Format("Jan 02, 3:04 PM"), s.EndTime.Local().Format("3:04 PM MST"))
-					if m.selected == title {
-						return m, loadSessionUsage(s, m.groupBy)
-					}
+				if m.session != nil {
+					return m, loadSessionUsage(*m.session, m.groupBy)
 				}
 			}
+		case key.Matches(msg, key.NewBinding(key.WithKeys("t"))):
+			if m.currentView == sessionListView && m.listReady && m.list.FilterState() != list.Filtering {
+				m.relativeTimes = !m.relativeTimes
+				return m, m.list.SetItems(m.sessionItems())
+			}
+		case key.Matches(msg, key.NewBinding(key.WithKeys("l"))):
+			if m.currentView == sessionListView && m.listReady && m.list.FilterState() != list.Filtering {
+				return m, m.startLabeling()
+			}
+		case key.Matches(msg, key.NewBinding(key.WithKeys("d"))):
+			if m.currentView == usageTableView || m.currentView == sessionUsageTableView || m.currentView == compareView {
+				m.dense = !m.dense
+				return m, nil
+			}
+		case key.Matches(msg, key.NewBinding(key.WithKeys(" "))):
+			if m.currentView == usageListView && m.listReady && m.list.FilterState() != list.Filtering {
+				return m, m.toggleMark()
+			}
+		case key.Matches(msg, key.NewBinding(key.WithKeys("c"))):
+			if (m.currentView == usageTableView || m.currentView == sessionUsageTableView) && len(m.usage) > 0 ||
+				m.currentView == compareView && !m.loading {
+				return m, m.copyTable()
+			}
+			if m.currentView == usageListView && m.listReady && m.list.FilterState() != list.Filtering {
+				return m, m.openCompare()
+			}
+		case key.Matches(msg, key.NewBinding(key.WithKeys("e"))):
+			if m.currentView == sessionUsageTableView && m.session != nil {
+				return m, exportSession(*m.session)
+			}
 		case key.Matches(msg, key.NewBinding(key.WithKeys("s"))):
 			if m.currentView != sessionListView {
 				m.currentView = sessionListView
-				return m, loadSessions
+				return m, m.startLoading(loadSessions)
 			}
 		case key.Matches(msg, key.NewBinding(key.WithKeys("u"))):
 			if m.currentView != usageListView {
@@ -400,74 +1786,136 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.selected = ""
 				m.usage = nil
 				m.sessions = nil
-				return m, loadUsageList
+				return m, m.startLoading(loadUsageList)
 			}
 		case key.Matches(msg, key.NewBinding(key.WithKeys("left", "esc"))):
-			if m.currentView == usageTableView || m.currentView == sessionUsageTableView {
+			if m.currentView == usageTableView || m.currentView == sessionUsageTableView || m.currentView == compareView {
 				prevView := usageListView
 				if m.currentView == sessionUsageTableView {
 					prevView = sessionListView
 				}
 				m.currentView = prevView
 				m.selected = ""
+				m.session = nil
 				m.usage = nil
+				m.allTime = nil
+				m.compare = [2][]stats.GroupedUsage{}
 				return m, nil
 			}
 			return m, tea.Quit
 		case key.Matches(msg, key.NewBinding(key.WithKeys("right", "enter"))):
-			if m.currentView == usageListView {
-				if item, ok := m.list.SelectedItem().(projectItem); ok {
-					m.selected = item.name
-					m.currentView = usageTableView
-					path := item.path
-					if item.name == "All Projects" {
-						path = ""
-					}
-					return m, loadUsage(path)
-				}
-			} else if m.currentView == sessionListView {
-				if item, ok := m.list.SelectedItem().(sessionItem); ok {
-					if !item.block.IsGap {
-						m.selected = item.Title()
-						m.currentView = sessionUsageTableView
-						return m, loadSessionUsage(item.block, m.groupBy)
-					}
+			if cmd, ok := m.openSelected(); ok {
+				return m, cmd
+			}
+		}
+
+	case tea.MouseMsg:
+		if (m.currentView != usageListView && m.currentView != sessionListView) ||
+			!m.listReady || m.list.FilterState() == list.Filtering {
+			break
+		}
+		if msg.Action != tea.MouseActionPress {
+			return m, nil
+		}
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			m.list.CursorUp()
+		case tea.MouseButtonWheelDown:
+			m.list.CursorDown()
+		case tea.MouseButtonLeft:
+			if index, ok := m.listIndexAt(msg.Y); ok {
+				m.list.Select(index)
+				if cmd, ok := m.openSelected(); ok {
+					return m, cmd
 				}
 			}
 		}
+		return m, nil
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 		if m.listReady {
-			h, v := appStyle.GetFrameSize()
+			h, v := styles.App.GetFrameSize()
 			m.list.SetSize(msg.Width-h, msg.Height-v-1)
 		}
 
 	case projectsLoadedMsg:
-		items := []list.Item{
-			projectItem{name: "All Projects", actualPath: "Aggregate usage across all projects"},
-		}
+		m.loading = false
+		m.warning = msg.warning
+		items := []list.Item{msg.all}
 		for _, p := range msg.projects {
-			items = append(items, projectItem{name: p.Name, path: p.Path, actualPath: p.ActualPath})
+			items = append(items, p)
 		}
 		m.updateList(items, "Usage by Project")
+		if cmd := m.restoreSelection(); cmd != nil {
+			return m, cmd
+		}
 
 	case sessionsLoadedMsg:
-		var items []list.Item
-		for _, s := range msg.sessions {
-			items = append(items, sessionItem{block: s})
+		m.loading = false
+		m.sessions = msg.sessions
+		m.warning = msg.warning
+		m.updateList(m.sessionItems(), "Session History")
+		if cmd := m.restoreSelection(); cmd != nil {
+			return m, cmd
 		}
-		m.updateList(items, "Session History")
+
+	case progressMsg:
+		// Drop updates from a load that was superseded
+		if msg.ch != m.progressCh {
+			return m, nil
+		}
+		m.progress = msg.progress
+		return m, waitForProgress(msg.ch)
+
+	case spinner.TickMsg:
+		if m.loading {
+			var cmd tea.Cmd
+			m.spinner, cmd = m.spinner.Update(msg)
+			return m, cmd
+		}
+		return m, nil
 
 	case usageLoadedMsg:
+		m.loading = false
 		if msg.err != nil {
 			m.err = msg.err
 		} else {
 			m.usage = msg.usage
+			m.hidden = msg.hidden
+			m.allTime = msg.allTime
+			m.warning = msg.warning
+			m.viewport.GotoTop()
+		}
+
+	case compareLoadedMsg:
+		m.loading = false
+		if msg.err != nil {
+			m.err = msg.err
+		} else {
+			m.compare = msg.usage
+			m.warning = msg.warning
+			m.viewport.GotoTop()
 		}
 
+	case noticeMsg:
+		m.notice = string(msg)
+		return m, clearNotice()
+
+	case activeBlockMsg:
+		m.active = msg.block
+		m.activeLoaded = true
+		return m, tea.Tick(gaugeRefresh, func(time.Time) tea.Msg { return gaugeTickMsg{} })
+
+	case gaugeTickMsg:
+		return m, loadActiveBlock
+
+	case clearNoticeMsg:
+		m.notice = ""
+
 	case errMsg:
+		m.loading = false
 		m.err = msg.err
 	}
 
@@ -477,11 +1925,94 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	}
 
+	// Arrow keys, paging and the mouse wheel scroll long tables
+	if (m.currentView == usageTableView || m.currentView == sessionUsageTableView) && len(m.usage) > 0 ||
+		m.currentView == compareView && !m.loading {
+		m.syncViewport()
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	}
+
 	return m, nil
 }
 
-func (m *model) updateList(items []list.Item, title string) {
+// startLoading marks the model as loading and runs load alongside the
+// spinner and progress updates, which come on a channel of the load's own
+func (m *model) startLoading(load tea.Cmd) tea.Cmd {
+	m.loading = true
+	m.progress = stats.Progress{}
+	m.progressCh = make(chan stats.Progress, 1)
+	return tea.Batch(withProgress(load, m.progressCh), waitForProgress(m.progressCh), m.spinner.Tick)
+}
+
+// loadingView shows a spinner and how many files and events have been parsed
+func (m model) loadingView(what string) string {
+	text := fmt.Sprintf("%s Loading %s...", m.spinner.View(), what)
+	if m.progress.FilesTotal > 0 {
+		text += fmt.Sprintf(" parsed %s events (%d/%d files)",
+			stats.FormatTokens(m.progress.Events), m.progress.FilesDone, m.progress.FilesTotal)
+	}
+	return text
+}
+
+// openSelected drills into the selected list item, reporting whether
+// the selection could be opened
+func (m *model) openSelected() (tea.Cmd, bool) {
+	switch m.currentView {
+	case usageListView:
+		if item, ok := m.list.SelectedItem().(projectItem); ok {
+			m.selected = item.name
+			m.currentView = usageTableView
+			project := item.project()
+			if item.name == "All Projects" {
+				project = stats.Project{}
+			}
+			return m.startLoading(loadUsage(project)), true
+		}
+	case sessionListView:
+		if item, ok := m.list.SelectedItem().(sessionItem); ok && !item.block.IsGap {
+			m.selected = item.Title()
+			m.session = &item.block
+			m.currentView = sessionUsageTableView
+			return loadSessionUsage(item.block, m.groupBy), true
+		}
+	}
+	return nil, false
+}
+
+// listIndexAt maps a screen row to the index of the list item drawn there
+func (m model) listIndexAt(y int) (int, bool) {
+	titleBar := m.list.Styles.TitleBar.Render(m.list.Styles.Title.Render(m.list.Title))
+	top := styles.App.GetPaddingTop() + lipgloss.Height(titleBar)
+	if y < top {
+		return 0, false
+	}
+
 	delegate := list.NewDefaultDelegate()
+	row := (y - top) / (delegate.Height() + delegate.Spacing())
+	if row >= m.list.Paginator.PerPage {
+		return 0, false
+	}
+
+	index := m.list.Paginator.Page*m.list.Paginator.PerPage + row
+	if index >= len(m.list.VisibleItems()) {
+		return 0, false
+	}
+	return index, true
+}
+
+// sessionItems builds list items for the loaded sessions
+func (m model) sessionItems() []list.Item {
+	var items []list.Item
+	for _, s := range m.sessions {
+		items = append(items, sessionItem{block: s, relative: m.relativeTimes, label: m.labels.get(s.ID)})
+	}
+	return items
+}
+
+func (m *model) updateList(items []list.Item, title string) {
+	delegate := styles.listDelegate()
 	delegate.ShowDescription = true
 
 	w, h := m.width-4, m.height-6
@@ -492,33 +2023,36 @@ func (m *model) updateList(items []list.Item, title string) {
 		h = 20
 	}
 
-	m.list = list.New(items, delegate, w, h)
+	m.list = list.New(items, markDelegate{delegate, m.marked}, w, h)
 	m.list.Title = title
 	m.list.SetShowHelp(false)
 	m.list.SetShowStatusBar(false)
 	m.list.SetShowPagination(false)
 	m.list.SetFilteringEnabled(true)
-	m.list.Styles.Title = titleStyle
+	m.list.Styles.Title = styles.Title
 	m.listReady = true
 }
 
 func (m model) View() string {
 	if m.err != nil {
-		return appStyle.Render(fmt.Sprintf("Error: %v\n\nPress q to quit", m.err))
+		return styles.App.Render(fmt.Sprintf("Error: %v\n\nPress q to quit", m.err))
 	}
 
-	help := helpStyle.Render("[u] usage • [s] sessions • [q] quit")
+	help := styles.Help.Render("[u] usage • [s] sessions • [q] quit")
 
 	switch m.currentView {
-	case usageTableView, sessionUsageTableView:
+	case usageTableView, sessionUsageTableView, compareView:
+		if m.loading {
+			return styles.App.Render(m.loadingView("usage"))
+		}
 		return m.renderTable()
 	case sessionListView, usageListView:
-		if !m.listReady {
+		if !m.listReady || m.loading {
 			loading := "usage"
 			if m.currentView == sessionListView {
 				loading = "sessions"
 			}
-			return appStyle.Render(fmt.Sprintf("Loading %s...", loading))
+			return styles.App.Render(m.loadingView(loading))
 		}
 
 		statusBar := fmt.Sprintf("%d items • page %d/%d", 
@@ -527,49 +2061,372 @@ func (m model) View() string {
 			m.list.Paginator.TotalPages)
 		
 		viewHelp := help
-		if m.currentView == usageListView {
-			viewHelp = helpStyle.Render("[→] select • [u] usage • [s] sessions • [/] filter • [q] quit")
+		if m.currentView == sessionListView {
+			viewHelp = styles.Help.Render("[→] select • [t] toggle times • [l] label • [u] usage • [s] sessions • [/] filter • [?] help • [q] quit")
+			if m.labeling {
+				viewHelp = m.labelInput.View() + "\n" + styles.Help.Render("[enter] save, blank to remove • [esc] cancel")
+			}
+		} else if m.currentView == usageListView {
+			viewHelp = styles.Help.Render("[→] select • [space] mark • [c] compare • [u] usage • [s] sessions • [/] filter • [?] help • [q] quit")
+		}
+		if m.notice != "" {
+			viewHelp = styles.Help.Render(m.notice+" • ") + viewHelp
+		}
+
+		statusBar = styles.Help.Render(statusBar)
+		if gauge := m.renderGauge(); gauge != "" {
+			statusBar = gauge + "\n" + statusBar
+		}
+		if (m.currentView == usageListView || m.currentView == sessionListView) && m.warning != "" {
+			statusBar += "\n" + styles.Warning.Render(m.warning)
 		}
 
-		return appStyle.Render(m.list.View() + "\n" + helpStyle.Render(statusBar) + "\n\n" + viewHelp)
+		return styles.App.Render(m.list.View() + "\n" + statusBar + "\n\n" + viewHelp)
+	case helpView:
+		return m.renderHelp()
 	default:
 		return ""
 	}
 }
 
+// keyHelp lists the key bindings available in each view, in display order
+var keyHelp = []struct {
+	section string
+	keys    [][2]string
+}{
+	{"Everywhere", [][2]string{
+		{"u", "usage by project"},
+		{"s", "session history"},
+		{"?", "toggle this help"},
+		{"q, ctrl+c", "quit"},
+	}},
+	{"Usage list", [][2]string{
+		{"↑/↓, wheel", "move selection"},
+		{"→, enter, click", "open project usage"},
+		{"space", "mark project to compare"},
+		{"c", "compare the two marked projects"},
+		{"/", "filter projects"},
+		{"←, esc", "quit"},
+	}},
+	{"Session list", [][2]string{
+		{"↑/↓, wheel", "move selection"},
+		{"→, enter, click", "open session usage"},
+		{"t", "toggle relative/absolute times"},
+		{"l", "label session"},
+		{"/", "filter sessions"},
+		{"←, esc", "quit"},
+	}},
+	{"Usage table", [][2]string{
+		{"←, esc", "back to list"},
+		{"c", "copy table as TSV"},
+		{"d", "toggle dense rows"},
+		{"↑/↓, pgup/pgdn", "scroll long tables"},
+		{"g", "group session by model/project"},
+		{"e", "export session to JSON"},
+	}},
+}
+
+// renderHelp draws the full-screen key reference
+func (m model) renderHelp() string {
+	keyStyle := lipgloss.NewStyle().Bold(true).Width(18)
+
+	var b strings.Builder
+	b.WriteString(styles.Title.Render("Keyboard Shortcuts") + "\n")
+	for _, section := range keyHelp {
+		b.WriteString("\n" + lipgloss.NewStyle().Bold(true).Underline(true).Render(section.section) + "\n")
+		for _, k := range section.keys {
+			b.WriteString("  " + keyStyle.Render(k[0]) + styles.Help.Render(k[1]) + "\n")
+		}
+	}
+	b.WriteString("\n" + styles.Help.Render("[?/esc] close • [q] quit"))
+
+	return styles.App.Render(b.String())
+}
+
 func (m model) renderTable() string {
-	if len(m.usage) == 0 {
-		return appStyle.Render(
-			titleStyle.Render(m.selected) + "\n\n" +
+	empty := len(m.usage) == 0
+	if m.currentView == compareView {
+		empty = len(m.compare[0]) == 0 && len(m.compare[1]) == 0
+	}
+	if empty {
+		return styles.App.Render(
+			styles.Title.Render(m.selected) + "\n\n" +
 				"No usage data found\n\n" +
-				helpStyle.Render("[←] back • [q] quit"),
+				styles.Help.Render("[←] back • [q] quit"),
 		)
 	}
 
-	width, _, _ := term.GetSize(os.Stdout.Fd())
+	// m is a copy, so the viewport can be brought up to date here
+	m.syncViewport()
+	header, _, footer, helpStr := m.tableParts()
+	if m.viewport.TotalLineCount() > m.viewport.Height {
+		helpStr = fmt.Sprintf("[↑/↓] scroll %d%% • %s", int(m.viewport.ScrollPercent()*100), helpStr)
+	}
+
+	return styles.App.Render(
+		header +
+			m.viewport.View() + "\n\n" +
+			footer +
+			styles.Help.Render(helpStr),
+	)
+}
+
+// syncViewport loads the current table into the viewport, sized to the
+// rows left between the pinned header and footer
+func (m *model) syncViewport() {
+	header, tbl, footer, _ := m.tableParts()
+
+	height := lipgloss.Height(tbl)
+	if m.height > 0 {
+		_, frame := styles.App.GetFrameSize()
+		// The header and footer end in a blank line, and a blank line and
+		// the help line follow the table
+		avail := m.height - frame - strings.Count(header, "\n") - strings.Count(footer, "\n") - 2
+		height = min(height, max(avail, 3))
+	}
+
+	m.viewport.Width = lipgloss.Width(tbl)
+	m.viewport.Height = height
+	m.viewport.SetContent(tbl)
+}
+
+// tableParts renders a table view's pinned header and footer, the table
+// that scrolls between them, and the key help
+func (m model) tableParts() (header, tbl, footer, helpStr string) {
+	// Size from the latest WindowSizeMsg, so tables reflow live on resize
+	width := m.width
 	if width == 0 {
-		width = m.width
+		width, _, _ = term.GetSize(os.Stdout.Fd())
 	}
 	if width == 0 {
 		width = 120
 	}
-	useShort := width < 100
+	useShort := width < narrowWidth
 
 	formatNum := func(n int) string {
-		if useShort {
-			return stats.FormatTokensShort(n)
+		return formatTokens(n, useShort)
+	}
+
+	headers, rows := m.tableRows(formatNum)
+
+	if width < stackedWidth {
+		tbl = renderStacked(headers, rows, m.dense)
+	} else {
+		cellStyle := lipgloss.NewStyle().Padding(0, 1)
+		if m.dense {
+			cellStyle = lipgloss.NewStyle().PaddingLeft(1)
 		}
-		return stats.FormatTokens(n)
+		// StyleFunc only gets indices, so look up each row's model color
+		// up front
+		modelColors := make([]lipgloss.TerminalColor, len(rows))
+		if len(headers) > 1 && headers[1] == "Model" {
+			for i, row := range rows {
+				if color, ok := styles.modelColor(row[1]); ok {
+					modelColors[i] = color
+				}
+			}
+		}
+		tbl = table.New().
+			Border(lipgloss.NormalBorder()).
+			BorderRow(!m.dense).
+			Headers(headers...).
+			Rows(rows...).
+			StyleFunc(func(row, col int) lipgloss.Style {
+				if col == 1 && row >= 0 && modelColors[row] != nil {
+					return cellStyle.Foreground(modelColors[row])
+				}
+				return cellStyle
+			}).
+			String()
+	}
+
+	helpStr = "[←] back • [c] copy • [d] dense • [?] help • [q] quit"
+	if m.currentView == sessionUsageTableView {
+		gStr := "project"
+		if m.groupBy == "project" {
+			gStr = "model"
+		}
+		helpStr = fmt.Sprintf("[g] group by %s • [e] export • %s", gStr, helpStr)
+	}
+	if m.notice != "" {
+		helpStr = m.notice + " • " + helpStr
+	}
+
+	header = styles.Title.Render(m.selected) + "\n\n"
+	if m.warning != "" {
+		header += styles.Warning.Render(m.warning) + "\n\n"
+	}
+	if m.currentView == usageTableView && m.allTime != nil {
+		rangeTotal := stats.SumUsage(m.usage)
+		if m.hidden != nil {
+			rangeTotal = stats.SumUsage(append(slices.Clone(m.usage), *m.hidden))
+		}
+		header += styles.Help.Render(fmt.Sprintf("All time: %s tokens • This range: %s tokens",
+			formatNum(m.allTime.TotalTokens()), formatNum(rangeTotal.TotalTokens()))) + "\n\n"
+	}
+	if m.currentView == sessionUsageTableView && m.session != nil {
+		header += m.renderSessionPanel(width) + "\n\n"
+	}
+
+	if m.currentView == usageTableView && len(m.usage) > 1 {
+		s := stats.PeriodStats(m.usage)
+		footer = styles.Help.Render(fmt.Sprintf("Busiest: %s (%s) • Quietest: %s (%s) • Avg: %s • Median: %s",
+			s.MaxPeriod, formatNum(s.Max), s.MinPeriod, formatNum(s.Min),
+			formatNum(s.Avg), formatNum(s.Median))) + "\n\n"
+	}
+
+	return header, tbl, footer, helpStr
+}
+
+// renderStacked draws a table for very narrow terminals as one block per
+// row, headed by its first cell, with the other fields listed beneath as
+// "Header  value" lines. Blank fields are left out. A row whose first cell
+// is blank, as for a period's second and later models, is nested in the
+// block above rather than given an empty heading.
+func renderStacked(headers []string, rows [][]string, dense bool) string {
+	labelWidth := 0
+	for _, h := range headers[1:] {
+		labelWidth = max(labelWidth, lipgloss.Width(h))
+	}
+	label := styles.Help.Width(labelWidth + 2)
+
+	sep := "\n\n"
+	if dense {
+		sep = "\n"
+	}
+	var blocks []string
+	for _, row := range rows {
+		nested := row[0] == "" && len(blocks) > 0
+		var lines []string
+		if !nested {
+			lines = append(lines, lipgloss.NewStyle().Bold(true).Render(row[0]))
+		}
+		for i, cell := range row[1:] {
+			if cell == "" || i+1 >= len(headers) {
+				continue
+			}
+			lines = append(lines, "  "+label.Render(headers[i+1])+cell)
+		}
+		block := strings.Join(lines, "\n")
+		if nested {
+			blocks[len(blocks)-1] += sep + block
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	return strings.Join(blocks, sep)
+}
+
+// renderGauge draws the active block's usage against --limit, colored by
+// how close it is
+func (m model) renderGauge() string {
+	if CLI.Limit <= 0 || !m.activeLoaded {
+		return ""
+	}
+
+	used := 0
+	if m.active != nil {
+		used = m.active.TotalTokens()
+	}
+	ratio := float64(used) / float64(CLI.Limit)
+
+	color := styles.Gauge[0]
+	switch {
+	case ratio >= 0.9:
+		color = styles.Gauge[2]
+	case ratio >= 0.75:
+		color = styles.Gauge[1]
+	}
+
+	bar := progress.New(progress.WithSolidFill(color), progress.WithWidth(30), progress.WithoutPercentage())
+	return bar.ViewAs(math.Min(ratio, 1)) + styles.Help.Render(fmt.Sprintf(" %.0f%% of %gh limit (%s / %s)",
+		ratio*100, CLI.SessionDuration.Hours(), formatTokens(used, true), formatTokens(CLI.Limit, true)))
+}
+
+// renderSessionPanel summarizes the selected session's timing, cost and
+// efficiency, collapsing to a single line on narrow terminals
+func (m model) renderSessionPanel(width int) string {
+	block := m.session
+
+	cost := stats.FormatCost(pricing.EventsCost(block.Entries))
+	burn := "-"
+	if rate := stats.CalculateBurnRate(block); rate != nil {
+		burn = fmt.Sprintf("%.1f tok/min", rate.TokensPerMinute)
+	}
+	cacheHit := fmt.Sprintf("%.1f%%", block.CacheHitRatio()*100)
+	saved := stats.FormatCost(cacheSavings(block.Entries))
+	cached := fmt.Sprintf("%.1f%% of tokens", block.CacheReadShare()*100)
+
+	if width < narrowWidth {
+		return styles.Help.Render(fmt.Sprintf("%s • %s • cache %s, saved %s • ", cost, burn, cacheHit, saved)) +
+			styles.Highlight.Render("cached "+cached)
+	}
+
+	start := block.StartTime.Local().Format("Jan 02, 3:04 PM")
+	end := block.ActualEndTime.Local().Format("3:04 PM MST")
+	fields := [][2]string{
+		{"Time", start + " - " + end},
+		{"Cost", cost},
+		{"Burn Rate", burn},
+		{"Cache Hit", cacheHit},
+		{"Cache Saved", saved},
+	}
+
+	var lines []string
+	for _, f := range fields {
+		lines = append(lines, fmt.Sprintf("%-12s %s", f[0]+":", f[1]))
+	}
+	// The one number that says whether the session was cheap for its size
+	lines = append(lines, fmt.Sprintf("%-12s %s", "Cache Reads:", styles.Highlight.Render(cached)))
+	return styles.Panel.Render(strings.Join(lines, "\n"))
+}
+
+// tableRows builds the header and body rows of the usage table, including
+// the trailing "Total" row
+func (m model) tableRows(formatNum func(int) string) ([]string, [][]string) {
+	if m.currentView == compareView {
+		return m.compareRows(formatNum)
 	}
 
 	var rows [][]string
-	var totalInput, totalOutput, totalCacheCreate, totalCacheRead int
+	var totalInput, totalOutput, totalCacheCreate, totalCache1h, totalCacheRead, totalZero, totalRequests int
+
+	// Only break out 1-hour cache writes when the logs record them
+	split1h := m.hidden != nil && m.hidden.CacheCreate1h > 0
+	for _, u := range m.usage {
+		if u.CacheCreate1h > 0 {
+			split1h = true
+		}
+	}
+	split1h = split1h && !CLI.MergeCache
+
+	// Cache columns are writes, 1-hour writes when split out, and reads,
+	// or one combined column with --merge-cache
+	cacheCells := func(write, write1h, read int) []string {
+		if CLI.MergeCache {
+			return []string{formatNum(write + read)}
+		}
+		if split1h {
+			return []string{formatNum(write), formatNum(write1h), formatNum(read)}
+		}
+		return []string{formatNum(write), formatNum(read)}
+	}
+
+	// A running total only reads well down a chronological table
+	cumulative := CLI.Cumulative && !(m.currentView == sessionUsageTableView && m.groupBy == "project")
+	running := 0
 
 	for _, u := range m.usage {
 		totalInput += u.InputTotal
 		totalOutput += u.OutputTotal
 		totalCacheCreate += u.CacheCreateTotal
+		totalCache1h += u.CacheCreate1h
 		totalCacheRead += u.CacheReadTotal
+		totalZero += u.ZeroEvents
+		totalRequests += u.Requests
+
+		periodTotal := u.InputTotal + u.OutputTotal + u.CacheCreateTotal + u.CacheReadTotal
+		running += periodTotal
 
 		for i, modelName := range u.Models {
 			mu := u.ByModel[modelName]
@@ -580,28 +2437,81 @@ func (m model) renderTable() string {
 				firstCol = u.Period
 			}
 
-			rows = append(rows, []string{
+			row := []string{
 				firstCol,
 				modelName,
 				formatNum(mu.Input),
 				formatNum(mu.Output),
-				formatNum(mu.CacheCreate),
-				formatNum(mu.CacheRead),
+			}
+			row = append(row, cacheCells(mu.CacheCreate, mu.CacheCreate1h, mu.CacheRead)...)
+			row = append(row,
 				formatNum(total),
-			})
+				formatShare(total, periodTotal),
+				strconv.Itoa(mu.Requests), // A count, not tokens, so never in --units
+				perRequest(total, mu.Requests, formatNum),
+			)
+			if cumulative {
+				if i == 0 {
+					row = append(row, formatNum(running))
+				} else {
+					row = append(row, "")
+				}
+			}
+			if CLI.KeepZero {
+				if i == 0 {
+					row = append(row, strconv.Itoa(u.ZeroEvents))
+				} else {
+					row = append(row, "")
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	// Periods hidden by --min-tokens share a row so the total adds up
+	if h := m.hidden; h != nil {
+		totalInput += h.InputTotal
+		totalOutput += h.OutputTotal
+		totalCacheCreate += h.CacheCreateTotal
+		totalCache1h += h.CacheCreate1h
+		totalCacheRead += h.CacheReadTotal
+		totalZero += h.ZeroEvents
+		totalRequests += h.Requests
+
+		row := []string{h.Period, "", formatNum(h.InputTotal), formatNum(h.OutputTotal)}
+		row = append(row, cacheCells(h.CacheCreateTotal, h.CacheCreate1h, h.CacheReadTotal)...)
+		row = append(row, formatNum(h.TotalTokens()), "", strconv.Itoa(h.Requests),
+			perRequest(h.TotalTokens(), h.Requests, formatNum))
+		if cumulative {
+			row = append(row, "")
+		}
+		if CLI.KeepZero {
+			row = append(row, strconv.Itoa(h.ZeroEvents))
 		}
+		rows = append(rows, row)
 	}
 
 	totalAll := totalInput + totalOutput + totalCacheCreate + totalCacheRead
-	rows = append(rows, []string{
+	totalRow := []string{
 		"Total",
 		"",
 		formatNum(totalInput),
 		formatNum(totalOutput),
-		formatNum(totalCacheCreate),
-		formatNum(totalCacheRead),
+	}
+	totalRow = append(totalRow, cacheCells(totalCacheCreate, totalCache1h, totalCacheRead)...)
+	totalRow = append(totalRow,
 		formatNum(totalAll),
-	})
+		"",
+		strconv.Itoa(totalRequests),
+		perRequest(totalAll, totalRequests, formatNum),
+	)
+	if cumulative {
+		totalRow = append(totalRow, "")
+	}
+	if CLI.KeepZero {
+		totalRow = append(totalRow, strconv.Itoa(totalZero))
+	}
+	rows = append(rows, totalRow)
 
 	firstHeader := "Period"
 	if m.currentView == sessionUsageTableView {
@@ -612,41 +2522,85 @@ func (m model) renderTable() string {
 		}
 	}
 
-	tbl := table.New().
-		Border(lipgloss.NormalBorder()).
-		BorderRow(true).
-		Headers(firstHeader, "Model", "Input", "Output", "Cache Write", "Cache Read", "Total").
-		Rows(rows...).
-		StyleFunc(func(row, col int) lipgloss.Style {
-			return lipgloss.NewStyle().Padding(0, 1)
-		})
+	headers := []string{firstHeader, "Model", "Input", "Output"}
+	switch {
+	case CLI.MergeCache:
+		headers = append(headers, "Cache")
+	case split1h:
+		headers = append(headers, "Cache Write", "Cache 1h", "Cache Read")
+	default:
+		headers = append(headers, "Cache Write", "Cache Read")
+	}
+	headers = append(headers, "Total", "Share", "Requests", "Avg/Req")
+	if cumulative {
+		headers = append(headers, "Cumulative")
+	}
+	if CLI.KeepZero {
+		headers = append(headers, "Zero")
+	}
+	return headers, rows
+}
 
-	title := titleStyle.Render(m.selected)
-	
-	helpStr := "[←] back • [q] quit"
-	if m.currentView == sessionUsageTableView {
-		helpStr = "[g] group by "
-		if m.groupBy == "model" {
-			helpStr += "project"
-		} else {
-			helpStr += "model"
+// formatShare formats part as a percentage of whole
+func formatShare(part, whole int) string {
+	if whole == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(part)/float64(whole)*100)
+}
+
+// perRequest formats the mean tokens per request
+func perRequest(tokens, requests int, formatNum func(int) string) string {
+	if requests == 0 {
+		return "-"
+	}
+	return formatNum(tokens / requests)
+}
+
+// tableTSV renders the usage table as tab-separated values with raw numbers
+func (m model) tableTSV() string {
+	headers, rows := m.tableRows(strconv.Itoa)
+
+	var b strings.Builder
+	b.WriteString(strings.Join(headers, "\t") + "\n")
+	for _, row := range rows {
+		b.WriteString(strings.Join(row, "\t") + "\n")
+	}
+	return b.String()
+}
+
+// copyTable copies the current table to the system clipboard
+func (m model) copyTable() tea.Cmd {
+	return func() tea.Msg {
+		if clipboard.Unsupported {
+			return noticeMsg("clipboard unavailable")
 		}
-		helpStr += " • " + helpStr
+		if err := clipboard.WriteAll(m.tableTSV()); err != nil {
+			return noticeMsg("clipboard unavailable")
+		}
+		return noticeMsg("copied!")
 	}
-	
-	// Fix: helpStr was using itself in the definition, let's fix that
-	helpStr = "[←] back • [q] quit"
-	if m.currentView == sessionUsageTableView {
-		gStr := "project"
-		if m.groupBy == "project" {
-			gStr = "model"
+}
+
+// exportSession writes the session to session-<id>.json in the current
+// directory
+func exportSession(block stats.SessionBlock) tea.Cmd {
+	return func() tea.Msg {
+		name := "session-" + strings.ReplaceAll(block.ID, ":", "") + ".json"
+		f, err := os.Create(name)
+		if err != nil {
+			return noticeMsg("export failed: " + err.Error())
 		}
-		helpStr = fmt.Sprintf("[g] group by %s • %s", gStr, helpStr)
+		defer f.Close()
+		if err := writeJSON(f, sessionOutput(block)); err != nil {
+			return noticeMsg("export failed: " + err.Error())
+		}
+		return noticeMsg("saved " + name)
 	}
+}
 
-	return appStyle.Render(
-		title + "\n\n" +
-			tbl.String() + "\n\n" +
-			helpStyle.Render(helpStr),
-	)
+func clearNotice() tea.Cmd {
+	return tea.Tick(2*time.Second, func(time.Time) tea.Msg {
+		return clearNoticeMsg{}
+	})
 }
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/montanaflynn/claudette/internal/stats"
+)
+
+func TestRenderStackedNestsModelsUnderPeriod(t *testing.T) {
+	headers := []string{"Period", "Model", "Input"}
+	rows := [][]string{
+		{"2025-03-01", "opus", "10"},
+		{"", "sonnet", "20"},
+		{"2025-03-02", "opus", "5"},
+	}
+
+	got := renderStacked(headers, rows, true)
+	want := strings.Join([]string{
+		"2025-03-01",
+		"  Model  opus",
+		"  Input  10",
+		"  Model  sonnet",
+		"  Input  20",
+		"2025-03-02",
+		"  Model  opus",
+		"  Input  5",
+	}, "\n")
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTableRowsRequestsIgnoreUnits(t *testing.T) {
+	CLI.Units = "m"
+	t.Cleanup(func() { CLI.Units = "" })
+
+	m := model{
+		currentView: usageTableView,
+		usage: []stats.GroupedUsage{{
+			Period: "2025-03-01", Models: []string{"opus"}, InputTotal: 3000000, Requests: 3,
+			ByModel: map[string]*stats.ModelUsage{"opus": {Model: "opus", Input: 3000000, Requests: 3}},
+		}},
+		hidden: &stats.GroupedUsage{Period: "2 hidden", InputTotal: 1000000, Requests: 2},
+	}
+	headers, rows := m.tableRows(func(n int) string { return formatTokens(n, false) })
+
+	col := -1
+	for i, h := range headers {
+		if h == "Requests" {
+			col = i
+		}
+	}
+	var got []string
+	for _, row := range rows {
+		got = append(got, row[col])
+	}
+	if strings.Join(got, ",") != "3,2,5" {
+		t.Errorf("got request counts %v, want 3, 2 and a total of 5", got)
+	}
+}
+
+func TestConfigResolvesFlags(t *testing.T) {
+	cfg := Config{
+		"pricing":          "/tmp/rates.json",
+		"active_threshold": "30m",
+		"limit":            float64(500000),
+		"every":            "10s",
+	}
+	saved := CLI
+	t.Cleanup(func() { CLI = saved })
+	parser, err := kong.New(&CLI, kong.Vars{"version": version}, kong.Resolvers(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.Parse([]string{"status"}); err != nil {
+		t.Fatal(err)
+	}
+	if CLI.Pricing != "/tmp/rates.json" || CLI.ActiveThreshold != 30*time.Minute || CLI.Limit != 500000 ||
+		CLI.Status.Every != 10*time.Second {
+		t.Errorf("got pricing %q, active threshold %s, limit %d, every %s; want each from the config",
+			CLI.Pricing, CLI.ActiveThreshold, CLI.Limit, CLI.Status.Every)
+	}
+}
+
+func TestNoModelsOnlyAppliesToCommandLine(t *testing.T) {
+	CLI.NoModels = true
+	t.Cleanup(func() { CLI.NoModels = false })
+
+	ts := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	block := stats.SessionBlock{
+		StartTime: ts,
+		EndTime:   ts.Add(5 * time.Hour),
+		Entries:   []stats.UsageEvent{{Timestamp: ts, InputTokens: 10, Model: "opus"}},
+	}
+	usage := stats.LoadGroupedUsageForEvents(block.Entries, "model")
+
+	if got := usageOutput(usage[0]); got.Models != nil {
+		t.Errorf("command-line output kept models %+v", got.Models)
+	}
+	if got := sessionOutput(block); len(got.Usage) != 1 || len(got.Usage[0].Models) != 1 {
+		t.Errorf("session export lost its models: %+v", got.Usage)
+	}
+}
+
+func TestUsageListPerFile(t *testing.T) {
+	stats.Roots = []string{filepath.Join("internal", "stats", "testdata", "projects")}
+	t.Cleanup(func() { stats.Roots = nil })
+
+	for _, perFile := range []bool{false, true} {
+		stats.PerFile = perFile
+		msg, ok := loadUsageList().(projectsLoadedMsg)
+		stats.PerFile = false
+		if !ok {
+			t.Fatalf("per-file %v: loading the list failed", perFile)
+		}
+		if len(msg.projects) != 2 {
+			t.Fatalf("per-file %v: got %d projects, want 2", perFile, len(msg.projects))
+		}
+		for _, item := range msg.projects {
+			events, err := stats.LoadProjectEvents(item.project())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := eventTokens(events); item.tokens != want || want == 0 {
+				t.Errorf("per-file %v: %s lists %d tokens, want %d as in its own view", perFile, item.name, item.tokens, want)
+			}
+		}
+	}
+}
+
+func TestSnapshotDiffRefusesOtherSettings(t *testing.T) {
+	stats.Roots = []string{filepath.Join("internal", "stats", "testdata", "projects")}
+	t.Cleanup(func() { stats.Roots = nil })
+
+	tests := []struct {
+		name     string
+		settings snapshotSettings
+		wantErr  string
+	}{
+		{"same", snapshotSettings{Group: "day"}, ""},
+		{"utc", snapshotSettings{Group: "day", UTC: true}, "--utc"},
+		{"tz", snapshotSettings{Group: "day", TZ: "Asia/Tokyo"}, "--tz Asia/Tokyo"},
+		{"per-file", snapshotSettings{Group: "day", PerFile: true}, "--per-file"},
+		{"exclude", snapshotSettings{Group: "day", ExcludeProject: []string{"tmp-*"}}, "--exclude-project tmp-*"},
+		{"exclude-filtered", snapshotSettings{Group: "day", ExcludeFiltered: true}, "--exclude-filtered"},
+		{"alias", snapshotSettings{Group: "day", Alias: map[string]string{"-home-dev-alpha": "A"}}, "--alias -home-dev-alpha=A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot.json")
+			data, err := json.Marshal(snapshotFile{JSONOutput: JSONOutput{Projects: []ProjectOutput{}}, snapshotSettings: tt.settings})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			err = diffSnapshot(path, "", "day")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("diff with the same settings failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want one naming %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsClaudeCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"claude"}, true},
+		{[]string{"/usr/local/bin/claude", "--resume"}, true},
+		{[]string{"node", "/usr/local/bin/claude"}, true},
+		{[]string{"node", "/usr/lib/node_modules/@anthropic-ai/claude-code/cli.js"}, true},
+		{[]string{"/usr/bin/node", "/home/me/.npm-global/lib/node_modules/@anthropic-ai/claude-code/cli.js", "-c"}, true},
+		{[]string{`C:\Users\me\AppData\Roaming\npm\claude.exe`}, true},
+		{[]string{"node.exe", `C:\Users\me\AppData\Roaming\npm\node_modules\@anthropic-ai\claude-code\cli.js`}, true},
+		{[]string{"CLAUDE.EXE"}, true},
+		{[]string{"claudette", "status"}, false},
+		{[]string{"vim", "notes.md", "claude"}, false},
+		{[]string{"node", "/srv/app/server.js"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isClaudeCommand(tt.args); got != tt.want {
+			t.Errorf("isClaudeCommand(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/montanaflynn/claudette/internal/stats"
+)
+
+// ModelSummaryOutput is the JSON shape for `models list`
+type ModelSummaryOutput struct {
+	Model           string      `json:"model"`
+	ShortName       string      `json:"short_name"`
+	Tokens          TokenCounts `json:"tokens"`
+	Cost            float64     `json:"cost_usd"`
+	CostPer1kOutput float64     `json:"cost_per_1k_output"`
+	FirstSeen       time.Time   `json:"first_seen"`
+	LastSeen        time.Time   `json:"last_seen"`
+}
+
+func listModels(projectFilter string) error {
+	var events []stats.UsageEvent
+	var err error
+
+	if projectFilter == "" {
+		events, err = stats.LoadAllEvents()
+	} else {
+		project, findErr := findProject(projectFilter)
+		if findErr != nil {
+			return findErr
+		}
+		events, err = stats.LoadProjectEvents(*project)
+	}
+	if err = warnPartial(err); err != nil {
+		return err
+	}
+
+	models := stats.SummarizeModels(events)
+
+	if CLI.JSON {
+		output := make([]ModelSummaryOutput, len(models))
+		for i, m := range models {
+			cost := pricing.SummaryCost(m)
+			output[i] = ModelSummaryOutput{
+				Model:     m.Model,
+				ShortName: m.ShortName,
+				Tokens: TokenCounts{
+					Input:        m.Input,
+					Output:       m.Output,
+					CacheWrite:   m.CacheCreate,
+					CacheWrite1h: m.CacheCreate1h,
+					CacheRead:    m.CacheRead,
+					Total:        m.TotalTokens(),
+				},
+				Cost:            cost,
+				CostPer1kOutput: stats.CostPer1kOutput(cost, m.Output),
+				FirstSeen:       m.FirstSeen,
+				LastSeen:        m.LastSeen,
+			}
+		}
+		return encodeJSON(output)
+	}
+
+	if len(models) == 0 {
+		fmt.Println("No usage data found")
+		return nil
+	}
+
+	width := len("Model")
+	for _, m := range models {
+		if len(m.Model) > width {
+			width = len(m.Model)
+		}
+	}
+
+	fmt.Printf("%-*s  %-12s  %16s  %10s  %10s  %-10s  %-10s\n",
+		width, "Model", "Normalized", "Total", "Cost", "$/1k Out", "First Seen", "Last Seen")
+	for _, m := range models {
+		cost := pricing.SummaryCost(m)
+		per1k := "-"
+		if m.Output > 0 {
+			per1k = fmt.Sprintf("$%.4f", stats.CostPer1kOutput(cost, m.Output))
+		}
+		fmt.Printf("%-*s  %-12s  %16s  %10s  %10s  %-10s  %-10s\n",
+			width, m.Model,
+			m.ShortName,
+			stats.FormatTokens(m.TotalTokens()),
+			stats.FormatCost(cost),
+			per1k,
+			m.FirstSeen.Local().Format("2006-01-02"),
+			m.LastSeen.Local().Format("2006-01-02"),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/montanaflynn/claudette/internal/stats"
+)
+
+// notifyThreshold is a --notify-at value: a token count, or a cost in USD
+// when given with a leading "$"
+type notifyThreshold struct {
+	tokens int
+	cost   float64
+	label  string
+}
+
+func parseNotifyThreshold(s string) (notifyThreshold, error) {
+	if amount, ok := strings.CutPrefix(s, "$"); ok {
+		cost, err := strconv.ParseFloat(amount, 64)
+		if err != nil || cost <= 0 {
+			return notifyThreshold{}, fmt.Errorf("invalid --notify-at cost %q: want e.g. $5", s)
+		}
+		return notifyThreshold{cost: cost, label: stats.FormatCost(cost)}, nil
+	}
+	tokens, err := strconv.Atoi(s)
+	if err != nil || tokens <= 0 {
+		return notifyThreshold{}, fmt.Errorf("invalid --notify-at %q: want a token count or a cost such as $5", s)
+	}
+	return notifyThreshold{tokens: tokens, label: stats.FormatTokens(tokens) + " tokens"}, nil
+}
+
+// reached reports whether the block has used the threshold's tokens or
+// cost
+func (t notifyThreshold) reached(block *stats.SessionBlock) bool {
+	if t.cost > 0 {
+		return pricing.EventsCost(block.Entries) >= t.cost
+	}
+	return block.TotalTokens() >= t.tokens
+}
+
+// limitNotifier notifies once for each threshold the active block
+// reaches, starting over when a new block begins
+type limitNotifier struct {
+	thresholds []notifyThreshold
+	block      string       // ID of the block being watched
+	fired      map[int]bool // Indexes of the thresholds already notified
+}
+
+func newLimitNotifier(values []string) (*limitNotifier, error) {
+	n := &limitNotifier{fired: make(map[int]bool)}
+	for _, v := range values {
+		t, err := parseNotifyThreshold(v)
+		if err != nil {
+			return nil, err
+		}
+		n.thresholds = append(n.thresholds, t)
+	}
+	return n, nil
+}
+
+// check notifies for the thresholds the active block has newly reached
+func (n *limitNotifier) check(active *stats.SessionBlock) {
+	if active == nil {
+		return
+	}
+	if active.ID != n.block {
+		n.block = active.ID
+		clear(n.fired)
+	}
+	for i, t := range n.thresholds {
+		if n.fired[i] || !t.reached(active) {
+			continue
+		}
+		n.fired[i] = true
+		body := fmt.Sprintf("The session that started at %s has used %s, %s",
+			active.StartTime.Local().Format("3:04 PM"),
+			formatTokens(active.TotalTokens(), true), stats.FormatCost(pricing.EventsCost(active.Entries)))
+		notify("Claudette: reached "+t.label, body)
+	}
+}
+
+// notify shows a desktop notification, ringing the terminal bell instead
+// where there's no way to show one
+func notify(title, body string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(body), strconv.Quote(title))
+		cmd = exec.Command("osascript", "-e", script)
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("notify-send", title, body)
+	}
+	if cmd == nil || cmd.Run() != nil {
+		fmt.Fprint(os.Stderr, "\a")
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// claudeRunning reports whether a Claude Code process is running, by
+// scanning /proc. ok is false when processes can't be listed.
+func claudeRunning() (running, ok bool) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return false, false
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.Trim(entry.Name(), "0123456789") != "" {
+			continue
+		}
+		cmdline, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		if isClaudeCommand(strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")) {
+			return true, true
+		}
+	}
+	return false, true
+}
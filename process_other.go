@@ -0,0 +1,24 @@
+//go:build !linux
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// claudeRunning reports whether a Claude Code process is running, using
+// ps. ok is false when processes can't be listed.
+func claudeRunning() (running, ok bool) {
+	out, err := exec.Command("ps", "-Ao", "command=").Output()
+	if err != nil {
+		return false, false
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if isClaudeCommand(strings.Fields(line)) {
+			return true, true
+		}
+	}
+	return false, true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"syscall"
+)
+
+// stopProfiling finishes the profiles started by --cpuprofile and
+// --memprofile. exit calls it too, so they're written however the program
+// ends.
+var stopProfiling = func() error { return nil }
+
+// exit stops profiling and exits with code, or 1 if the profiles couldn't
+// be written. It's kong's exit function, so fatal errors reach it as well.
+func exit(code int) {
+	if err := stopProfiling(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		code = max(code, 1)
+	}
+	os.Exit(code)
+}
+
+// startProfiling starts a CPU profile written to cpuPath, if set. The
+// returned function stops it and, if memPath is set, writes a heap
+// profile there; only the first call does anything. While profiling, an
+// interrupt stops it before exiting.
+func startProfiling(cpuPath, memPath string) (stop func() error, err error) {
+	if cpuPath == "" && memPath == "" {
+		return stopProfiling, nil
+	}
+
+	var cpu *os.File
+	if cpuPath != "" {
+		if cpu, err = os.Create(cpuPath); err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(cpu); err != nil {
+			cpu.Close()
+			return nil, err
+		}
+	}
+
+	go func() {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		<-interrupt
+		exit(130)
+	}()
+
+	return sync.OnceValue(func() error {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			if err := cpu.Close(); err != nil {
+				return err
+			}
+		}
+		if memPath == "" {
+			return nil
+		}
+		f, err := os.Create(memPath)
+		if err != nil {
+			return err
+		}
+		// Collect first so the profile shows live memory
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}), nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/montanaflynn/claudette/internal/stats"
+)
+
+// showReport prints estimated cost per period as CSV, with a grand total
+// row, for attaching to expense claims
+func showReport(projectFilter, groupBy string) error {
+	if !isPeriodGroup(groupBy) && !CLI.Report.Text {
+		return fmt.Errorf("report groups by time period: expected one of %s", joinPeriodGroups())
+	}
+
+	var events []stats.UsageEvent
+	var err error
+
+	if projectFilter == "" {
+		events, err = stats.LoadAllEvents()
+	} else {
+		project, findErr := findProject(projectFilter)
+		if findErr != nil {
+			return findErr
+		}
+		events, err = stats.LoadProjectEvents(*project)
+	}
+	if err = warnPartial(err); err != nil {
+		return err
+	}
+
+	if CLI.Report.Text {
+		fmt.Print(weeklyReport(events, time.Now()))
+		return nil
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{groupBy, "input_cost", "output_cost", "cache_cost", "total_cost"})
+
+	// Round each cell first so every row and the total row add up exactly
+	var total stats.CostBreakdown
+	for _, pc := range pricing.PeriodCosts(events, groupBy) {
+		c := roundCents(pc.CostBreakdown)
+		total = total.Add(c)
+		w.Write(costRecord(pc.Period, c))
+	}
+	w.Write(costRecord("total", total))
+
+	w.Flush()
+	return w.Error()
+}
+
+func roundCents(c stats.CostBreakdown) stats.CostBreakdown {
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	return stats.CostBreakdown{Input: round(c.Input), Output: round(c.Output), Cache: round(c.Cache)}
+}
+
+func costRecord(period string, c stats.CostBreakdown) []string {
+	return []string{
+		period,
+		fmt.Sprintf("%.2f", c.Input),
+		fmt.Sprintf("%.2f", c.Output),
+		fmt.Sprintf("%.2f", c.Cache),
+		fmt.Sprintf("%.2f", c.Total()),
+	}
+}
+
+// weeklyReport summarizes the last 7 days as plain text, clean to paste
+// into chat or email: totals and cost against the week before, the top
+// projects and the busiest day
+func weeklyReport(events []stats.UsageEvent, now time.Time) string {
+	y, m, d := now.Date()
+	start := time.Date(y, m, d-6, 0, 0, 0, 0, now.Location())
+	week := eventsBetween(events, start, time.Time{})
+	previous := eventsBetween(events, start.AddDate(0, 0, -7), start)
+
+	tokens, cost := eventTokens(week), pricing.EventsCost(week)
+	prevTokens, prevCost := eventTokens(previous), pricing.EventsCost(previous)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Claude usage, %s to %s\n\n", start.Format("Mon Jan 2"), now.Format("Mon Jan 2"))
+	fmt.Fprintf(&b, "Total: %s tokens, %s\n", stats.FormatTokensShort(tokens), stats.FormatCost(cost))
+	if prevTokens > 0 {
+		delta := stats.FormatTokensShort(tokens - prevTokens)
+		if tokens > prevTokens {
+			delta = "+" + delta
+		}
+		fmt.Fprintf(&b, "Week over week: %s tokens (%s), %s cost (was %s)\n",
+			delta, percentChange(tokens, prevTokens),
+			percentChange(cost, prevCost), stats.FormatCost(prevCost))
+	} else {
+		b.WriteString("Week over week: no usage the week before\n")
+	}
+
+	projects := stats.LoadGroupedUsageForEvents(week, "project")
+	sort.SliceStable(projects, func(i, j int) bool {
+		return projects[i].TotalTokens() > projects[j].TotalTokens()
+	})
+	if len(projects) > 0 {
+		b.WriteString("\nTop projects:\n")
+		for i, p := range projects[:min(3, len(projects))] {
+			fmt.Fprintf(&b, "%d. %s: %s tokens, %s\n", i+1, p.Period,
+				stats.FormatTokensShort(p.TotalTokens()), stats.FormatCost(pricing.UsageCost(p)))
+		}
+	}
+
+	if days := stats.LoadGroupedUsageForEvents(week, "day"); len(days) > 0 {
+		s := stats.PeriodStats(days)
+		busiest, _ := time.ParseInLocation("2006-01-02", s.MaxPeriod, time.Local)
+		fmt.Fprintf(&b, "\nBusiest day: %s, %s tokens\n", busiest.Format("Monday Jan 2"), stats.FormatTokensShort(s.Max))
+	}
+	return b.String()
+}
+
+// percentChange formats the change from before to now as a signed
+// percentage, e.g. "+12%"
+func percentChange[T int | float64](now, before T) string {
+	if before == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.0f%%", (float64(now)/float64(before)-1)*100)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tuiState is where the TUI was left, saved on exit and restored on the
+// next launch with --resume
+type tuiState struct {
+	View    string `json:"view"`           // "usage" or "sessions"
+	Item    string `json:"item,omitempty"` // Selected project name or session ID
+	Open    bool   `json:"open"`           // Whether the item's table was open
+	GroupBy string `json:"group_by,omitempty"`
+}
+
+// statePath returns the file the TUI state is saved to
+func statePath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tui-state.json"), nil
+}
+
+// loadState reads the saved TUI state, returning nil when there is none
+func loadState() *tuiState {
+	path, err := statePath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var state tuiState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil
+	}
+	return &state
+}
+
+// saveState writes the TUI state for the next --resume
+func saveState(state tuiState) error {
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// state captures the current view and selection
+func (m model) state() tuiState {
+	view := m.currentView
+	if view == helpView {
+		view = m.prevView
+	}
+
+	state := tuiState{View: "usage", GroupBy: m.groupBy}
+	switch view {
+	case usageListView:
+		if item, ok := m.selectedItem().(projectItem); ok {
+			state.Item = item.name
+		}
+	case usageTableView:
+		state.Item = m.selected
+		state.Open = true
+	case sessionListView:
+		state.View = "sessions"
+		if item, ok := m.selectedItem().(sessionItem); ok {
+			state.Item = item.block.ID
+		}
+	case sessionUsageTableView:
+		state.View = "sessions"
+		if m.session != nil {
+			state.Item = m.session.ID
+			state.Open = true
+		}
+	}
+	return state
+}
+
+// selectedItem returns the list's selected item, nil before it is ready
+func (m model) selectedItem() list.Item {
+	if !m.listReady {
+		return nil
+	}
+	return m.list.SelectedItem()
+}
+
+// restoreSelection selects the item saved by --resume once its list has
+// loaded, opening it if it was open, and then forgets the saved state
+func (m *model) restoreSelection() tea.Cmd {
+	state := m.resume
+	m.resume = nil
+	if state == nil || state.Item == "" {
+		return nil
+	}
+
+	for i, item := range m.list.Items() {
+		var id string
+		switch item := item.(type) {
+		case projectItem:
+			id = item.name
+		case sessionItem:
+			id = item.block.ID
+		}
+		if id != state.Item {
+			continue
+		}
+		m.list.Select(i)
+		if state.Open {
+			cmd, _ := m.openSelected()
+			return cmd
+		}
+		return nil
+	}
+	return nil
+}
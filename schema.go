@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// schemaOutputs maps `schema` arguments to the value each JSON output
+// encodes
+var schemaOutputs = map[string]interface{}{
+	"usage":   JSONOutput{},
+	"tree":    TreeOutput{},
+	"daily":   []UsageOutput{},
+	"models":  []ModelSummaryOutput{},
+	"session": SessionOutput{},
+	"status":  StatusOutput{},
+	"doctor":  DoctorOutput{},
+	"diff":    SnapshotDiffOutput{},
+	"heatmap": [7][24]int{},
+}
+
+func schemaNames() []string {
+	var names []string
+	for name := range schemaOutputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// showSchema prints the JSON Schema for one of the JSON outputs, generated
+// from the output structs so the two can't drift
+func showSchema(name string) error {
+	v, ok := schemaOutputs[name]
+	if !ok {
+		return fmt.Errorf("unknown output %q: expected one of %s", name, strings.Join(schemaNames(), ", "))
+	}
+
+	b := schemaBuilder{defs: make(map[string]interface{})}
+	schema := b.schemaFor(reflect.TypeOf(v))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	if len(b.defs) > 0 {
+		schema["$defs"] = b.defs
+	}
+	return encodeJSON(schema)
+}
+
+// schemaBuilder collects named structs into $defs as it walks types
+type schemaBuilder struct {
+	defs map[string]interface{}
+}
+
+func (b *schemaBuilder) schemaFor(t reflect.Type) map[string]interface{} {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t == reflect.TypeOf(TokenCounts{}) && CLI.MergeCache {
+		t = reflect.TypeOf(MergedTokenCounts{})
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schemaFor(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": b.schemaFor(t.Elem())}
+	case reflect.Array:
+		return map[string]interface{}{
+			"type":     "array",
+			"items":    b.schemaFor(t.Elem()),
+			"minItems": t.Len(),
+			"maxItems": t.Len(),
+		}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaFor(t.Elem())}
+	case reflect.Struct:
+		ref := map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+		if _, ok := b.defs[t.Name()]; ok {
+			return ref
+		}
+		b.defs[t.Name()] = nil // Placeholder in case the type refers to itself
+		b.defs[t.Name()] = b.structSchema(t)
+		return ref
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = b.schemaFor(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/montanaflynn/claudette/internal/stats"
+)
+
+// SnapshotDiffOutput is the JSON shape for `snapshot diff`
+type SnapshotDiffOutput struct {
+	Projects []ProjectDiffOutput `json:"projects"`
+	Delta    int                 `json:"delta"` // Change in total tokens across every project
+}
+
+type ProjectDiffOutput struct {
+	Name    string             `json:"name"`
+	Periods []PeriodDiffOutput `json:"periods"`
+	Delta   int                `json:"delta"`
+}
+
+type PeriodDiffOutput struct {
+	Period string       `json:"period"`
+	Change string       `json:"change"`           // added, removed or changed
+	Before *TokenCounts `json:"before,omitempty"` // Omitted for added periods
+	After  *TokenCounts `json:"after,omitempty"`  // Omitted for removed periods
+	Delta  int          `json:"delta"`
+}
+
+// snapshotFile is a saved snapshot: usage as printed by --json, with the
+// settings that shaped it, which a diff must match
+type snapshotFile struct {
+	JSONOutput
+	snapshotSettings
+}
+
+// snapshotSettings are the options that decide a snapshot's periods and
+// project names
+type snapshotSettings struct {
+	Group           string            `json:"group"`
+	MinTokens       int               `json:"min_tokens"`
+	TZ              string            `json:"tz,omitempty"`
+	UTC             bool              `json:"utc,omitempty"`
+	PerFile         bool              `json:"per_file,omitempty"`
+	ExcludeProject  []string          `json:"exclude_project,omitempty"`
+	ExcludeFiltered bool              `json:"exclude_filtered,omitempty"`
+	Alias           map[string]string `json:"alias,omitempty"`
+}
+
+// currentSnapshotSettings returns the settings usage is grouped by now
+func currentSnapshotSettings(groupBy string) snapshotSettings {
+	return snapshotSettings{
+		Group:           groupBy,
+		MinTokens:       CLI.MinTokens,
+		TZ:              CLI.TZ,
+		UTC:             CLI.UTC,
+		PerFile:         CLI.PerFile,
+		ExcludeProject:  CLI.ExcludeProject,
+		ExcludeFiltered: CLI.ExcludeFiltered,
+		Alias:           CLI.Alias,
+	}
+}
+
+// flags spells the settings out as command-line flags, in a fixed order so
+// two settings can be compared by their flags
+func (s snapshotSettings) flags() string {
+	flags := []string{"--group " + s.Group, fmt.Sprintf("--min-tokens %d", s.MinTokens)}
+	if s.TZ != "" {
+		flags = append(flags, "--tz "+s.TZ)
+	}
+	if s.UTC {
+		flags = append(flags, "--utc")
+	}
+	if s.PerFile {
+		flags = append(flags, "--per-file")
+	}
+	patterns := slices.Clone(s.ExcludeProject)
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		flags = append(flags, "--exclude-project "+pattern)
+	}
+	if s.ExcludeFiltered {
+		flags = append(flags, "--exclude-filtered")
+	}
+	dirs := make([]string, 0, len(s.Alias))
+	for dir := range s.Alias {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		flags = append(flags, "--alias "+dir+"="+s.Alias[dir])
+	}
+	return strings.Join(flags, " ")
+}
+
+// saveSnapshot writes current usage, as printed by --json, to path
+func saveSnapshot(path, projectFilter, groupBy string) error {
+	if strings.Contains(groupBy, ",") {
+		return fmt.Errorf("snapshots group by a single level, not %q", groupBy)
+	}
+	// Snapshots keep cache writes and reads apart so they can be diffed
+	CLI.MergeCache = false
+
+	output, err := usageJSON(projectFilter, groupBy)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(f, snapshotFile{JSONOutput: output, snapshotSettings: currentSnapshotSettings(groupBy)}); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Saved snapshot of %d projects to %s\n", len(output.Projects), path)
+	return nil
+}
+
+// diffSnapshot compares current usage with the snapshot at path, grouped
+// the same way, printing the periods added, removed or changed since
+func diffSnapshot(path, projectFilter, groupBy string) error {
+	if strings.Contains(groupBy, ",") {
+		return fmt.Errorf("snapshots group by a single level, not %q", groupBy)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var before snapshotFile
+	if err := json.Unmarshal(data, &before); err != nil {
+		return fmt.Errorf("reading snapshot %s: %w", path, err)
+	}
+	// Snapshots from before the settings were saved can't be checked
+	saved := before.snapshotSettings.flags()
+	if before.Group != "" && saved != currentSnapshotSettings(groupBy).flags() {
+		return fmt.Errorf("snapshot %s was saved with %s; diff it with the same settings", path, saved)
+	}
+
+	after, err := usageJSON(projectFilter, groupBy)
+	if err != nil {
+		return err
+	}
+
+	out := snapshotDiff(before.JSONOutput, after, projectFilter)
+	if CLI.JSON {
+		return encodeJSON(out)
+	}
+	printSnapshotDiff(out)
+	return nil
+}
+
+// snapshotDiff diffs two usage outputs project by project. With
+// projectFilter set, other projects in the snapshot are ignored.
+func snapshotDiff(before, after JSONOutput, projectFilter string) SnapshotDiffOutput {
+	usage := make(map[string][2][]stats.GroupedUsage)
+	var names []string
+	add := func(output JSONOutput, side int) {
+		for _, p := range output.Projects {
+			if projectFilter != "" && p.Name != projectFilter {
+				continue
+			}
+			pair, ok := usage[p.Name]
+			if !ok {
+				names = append(names, p.Name)
+			}
+			for _, u := range p.Usage {
+				pair[side] = append(pair[side], groupedFromOutput(u))
+			}
+			usage[p.Name] = pair
+		}
+	}
+	add(before, 0)
+	add(after, 1)
+	slices.Sort(names)
+
+	out := SnapshotDiffOutput{Projects: []ProjectDiffOutput{}}
+	for _, name := range names {
+		pair := usage[name]
+		diffs := stats.DiffUsage(pair[0], pair[1])
+		if len(diffs) == 0 {
+			continue
+		}
+		project := ProjectDiffOutput{Name: name}
+		for _, d := range diffs {
+			period := PeriodDiffOutput{Period: d.Period, Change: "changed", Delta: d.Delta()}
+			if d.Before != nil {
+				totals := usageOutput(*d.Before).Totals
+				period.Before = &totals
+			} else {
+				period.Change = "added"
+			}
+			if d.After != nil {
+				totals := usageOutput(*d.After).Totals
+				period.After = &totals
+			} else {
+				period.Change = "removed"
+			}
+			project.Periods = append(project.Periods, period)
+			project.Delta += period.Delta
+		}
+		out.Projects = append(out.Projects, project)
+		out.Delta += project.Delta
+	}
+	return out
+}
+
+// groupedFromOutput recovers a period's token totals from its JSON output
+func groupedFromOutput(u UsageOutput) stats.GroupedUsage {
+	return stats.GroupedUsage{
+		Period:           u.Period,
+		InputTotal:       u.Totals.Input,
+		OutputTotal:      u.Totals.Output,
+		CacheCreateTotal: u.Totals.CacheWrite,
+		CacheCreate1h:    u.Totals.CacheWrite1h,
+		CacheReadTotal:   u.Totals.CacheRead,
+		Requests:         u.Requests,
+	}
+}
+
+// printSnapshotDiff prints each changed period as "+" for added, "-" for
+// removed or "~" for changed, with its change in tokens
+func printSnapshotDiff(out SnapshotDiffOutput) {
+	if len(out.Projects) == 0 {
+		fmt.Println("No changes since the snapshot")
+		return
+	}
+
+	for _, p := range out.Projects {
+		fmt.Printf("%s (%s tokens)\n", p.Name, signedTokens(p.Delta))
+		for _, d := range p.Periods {
+			switch d.Change {
+			case "added":
+				fmt.Printf("  + %-20s %s tokens\n", d.Period, stats.FormatTokens(d.After.Total))
+			case "removed":
+				fmt.Printf("  - %-20s %s tokens\n", d.Period, stats.FormatTokens(d.Before.Total))
+			default:
+				fmt.Printf("  ~ %-20s %s → %s (%s)\n", d.Period,
+					stats.FormatTokens(d.Before.Total), stats.FormatTokens(d.After.Total), signedTokens(d.Delta))
+			}
+		}
+	}
+	fmt.Printf("Total: %s tokens\n", signedTokens(out.Delta))
+}
+
+// signedTokens formats a token change with its sign, e.g. "+1,234"
+func signedTokens(n int) string {
+	if n > 0 {
+		return "+" + stats.FormatTokens(n)
+	}
+	return stats.FormatTokens(n)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/montanaflynn/claudette/internal/stats"
+)
+
+// SummaryOutput is the JSON shape for `summary`
+type SummaryOutput struct {
+	Totals           TokenCounts     `json:"totals"`
+	TotalCost        float64         `json:"total_cost_usd"`
+	MonthToDateCost  float64         `json:"month_to_date_cost_usd"`
+	DailyAverageCost float64         `json:"daily_average_cost_usd"`
+	AverageDays      int             `json:"average_days"`
+	ProjectedCost    float64         `json:"projected_month_cost_usd"`
+	HourlyTokens     [24]int         `json:"hourly_tokens"`                // Tokens by local hour of day, midnight first
+	BusiestHour      *int            `json:"busiest_hour,omitempty"`       // Omitted when there is no usage
+	BusiestHourShare float64         `json:"busiest_hour_share,omitempty"` // Fraction of all tokens in the busiest hour
+	DailyCosts       []DayCostOutput `json:"daily_costs"`                  // The last 30 days, oldest first
+	CacheSavings     float64         `json:"cache_savings_usd"`            // Saved by cache reads over uncached input
+	WeekCacheSavings float64         `json:"week_cache_savings_usd"`       // The same over the last 7 days
+	CurrentStreak    int             `json:"current_streak"`               // Consecutive days with usage up to today
+	LongestStreak    int             `json:"longest_streak"`               // The most consecutive days with usage
+}
+
+type DayCostOutput struct {
+	Date string  `json:"date"`
+	Cost float64 `json:"cost_usd"`
+}
+
+// sparklineDays is how many days the summary's cost sparkline covers
+const sparklineDays = 30
+
+// costProjection is this month's cost so far and its projected total
+type costProjection struct {
+	monthToDate  float64
+	dailyAverage float64
+	projected    float64
+}
+
+func projectCosts(daily []stats.DailyUsage, now time.Time) costProjection {
+	return costProjection{
+		monthToDate:  stats.MonthToDateCost(daily, pricing, now),
+		dailyAverage: stats.AverageDailyCost(daily, pricing, now),
+		projected:    stats.ProjectMonthlyCost(daily, pricing, now),
+	}
+}
+
+func showSummary(projectFilter string) error {
+	var events []stats.UsageEvent
+	var err error
+
+	if projectFilter == "" {
+		events, err = stats.LoadAllEvents()
+	} else {
+		project, findErr := findProject(projectFilter)
+		if findErr != nil {
+			return findErr
+		}
+		events, err = stats.LoadProjectEvents(*project)
+	}
+	if err = warnPartial(err); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	sum := stats.SumUsage(stats.LoadGroupedUsageForEvents(events, "day"))
+	total := usageOutput(sum).Totals
+	savings := stats.CacheSavings(sum, pricing)
+	weekSavings := cacheSavings(recentEvents(events, now, 7))
+	totalCost := pricing.EventsCost(events)
+	daily := stats.DailyUsageForEvents(events)
+	projection := projectCosts(daily, now)
+	recent := stats.RecentDailyCosts(daily, pricing, now, sparklineDays)
+	heatmap := stats.BuildHeatmap(events)
+	hours := heatmap.ByHour()
+	busiest, share, ok := stats.BusiestHour(hours)
+	streak, longestStreak := stats.Streaks(daily, now)
+
+	if CLI.JSON {
+		out := SummaryOutput{
+			Totals:           total,
+			TotalCost:        totalCost,
+			MonthToDateCost:  projection.monthToDate,
+			DailyAverageCost: projection.dailyAverage,
+			AverageDays:      stats.ProjectionDays,
+			ProjectedCost:    projection.projected,
+			HourlyTokens:     hours,
+			DailyCosts:       make([]DayCostOutput, len(recent)),
+			CacheSavings:     savings,
+			WeekCacheSavings: weekSavings,
+			CurrentStreak:    streak,
+			LongestStreak:    longestStreak,
+		}
+		for i, d := range recent {
+			out.DailyCosts[i] = DayCostOutput{Date: d.Date, Cost: d.Cost}
+		}
+		if ok {
+			out.BusiestHour = &busiest
+			out.BusiestHourShare = share
+		}
+		return encodeJSON(out)
+	}
+
+	fmt.Printf("All time:      %s tokens, %s\n", stats.FormatTokens(total.Total), stats.FormatCost(totalCost))
+	if savings > 0 {
+		fmt.Printf("Cache saved:   %s all time, %s in the last 7 days\n", stats.FormatCost(savings), stats.FormatCost(weekSavings))
+	}
+	printProjection(projection, now)
+	printCostSparkline(recent)
+	if longestStreak > 0 {
+		current := streakDays(streak)
+		if streak > 0 {
+			current += " 🔥"
+		}
+		fmt.Printf("Streak:        %s, longest %s\n", current, streakDays(longestStreak))
+	}
+	if ok {
+		fmt.Printf("You use Claude most around %s (%.1f%% of tokens)\n", hourRange(busiest), share*100)
+	}
+	return nil
+}
+
+// streakDays formats a streak's length, e.g. "5 days"
+func streakDays(n int) string {
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
+
+// cacheSavings returns what cache reads saved across events compared with
+// paying for them as input
+func cacheSavings(events []stats.UsageEvent) float64 {
+	return stats.CacheSavings(stats.SumUsage(stats.LoadGroupedUsageForEvents(events, "day")), pricing)
+}
+
+// recentEvents returns the events from the last days days, today included
+func recentEvents(events []stats.UsageEvent, now time.Time, days int) []stats.UsageEvent {
+	y, m, d := now.Date()
+	since := time.Date(y, m, d-days+1, 0, 0, 0, 0, now.Location())
+	return eventsBetween(events, since, time.Time{})
+}
+
+// eventsBetween returns the events from from up to, but not including, to.
+// A zero to has no end.
+func eventsBetween(events []stats.UsageEvent, from, to time.Time) []stats.UsageEvent {
+	var between []stats.UsageEvent
+	for _, e := range events {
+		if !e.Timestamp.Before(from) && (to.IsZero() || e.Timestamp.Before(to)) {
+			between = append(between, e)
+		}
+	}
+	return between
+}
+
+// printCostSparkline prints recent daily costs as a sparkline, labelled
+// with the cheapest and most expensive days
+func printCostSparkline(days []stats.DayCost) {
+	if len(days) == 0 {
+		return
+	}
+	costs := make([]float64, len(days))
+	minDay, maxDay := days[0], days[0]
+	for i, d := range days {
+		costs[i] = d.Cost
+		if d.Cost < minDay.Cost {
+			minDay = d
+		}
+		if d.Cost > maxDay.Cost {
+			maxDay = d
+		}
+	}
+
+	fmt.Printf("Last %d days:  %s\n", len(days), sparkline(costs))
+	fmt.Printf("               high %s on %s, low %s on %s\n",
+		stats.FormatCost(maxDay.Cost), shortDate(maxDay.Date),
+		stats.FormatCost(minDay.Cost), shortDate(minDay.Date))
+}
+
+// sparkline draws values as a row of block characters scaled to the
+// largest, with zero as the lowest block
+func sparkline(values []float64) string {
+	blocks := []rune("▁▂▃▄▅▆▇█")
+	peak := 0.0
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if peak > 0 {
+			i = int(math.Round(v / peak * float64(len(blocks)-1)))
+		}
+		b.WriteRune(blocks[i])
+	}
+	return b.String()
+}
+
+// shortDate formats a YYYY-MM-DD date as e.g. "Mar 3"
+func shortDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format("Jan 2")
+}
+
+// hourRange formats the hour starting at hour as a 12-hour clock range,
+// e.g. "2–3 PM" or "11 AM–12 PM"
+func hourRange(hour int) string {
+	start := time.Date(2000, 1, 1, hour, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	if start.Format("PM") == end.Format("PM") {
+		return start.Format("3") + "–" + end.Format("3 PM")
+	}
+	return start.Format("3 PM") + "–" + end.Format("3 PM")
+}
+
+// printProjection prints this month's cost so far, the recent daily
+// average and the projected month total
+func printProjection(p costProjection, now time.Time) {
+	fmt.Printf("This month:    %s so far\n", stats.FormatCost(p.monthToDate))
+	fmt.Printf("Daily average: %s (last %d days)\n", stats.FormatCost(p.dailyAverage), stats.ProjectionDays)
+	fmt.Printf("Projected:     %s for %s\n", stats.FormatCost(p.projected), now.Format("January"))
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/montanaflynn/claudette/internal/stats"
+)
+
+// showTail follows the most recently written log, printing each usage
+// event as it arrives with a running total for the session. When Claude
+// Code starts a new log in the project, it follows that one instead.
+func showTail(projectFilter string) error {
+	var projects []stats.Project
+	if projectFilter != "" {
+		project, err := findProject(projectFilter)
+		if err != nil {
+			return err
+		}
+		projects = []stats.Project{*project}
+	} else {
+		var err error
+		if projects, err = stats.ListProjects(); err != nil {
+			return err
+		}
+	}
+
+	path, project, err := stats.LatestLog(projects)
+	if err != nil {
+		return err
+	}
+	projects = []stats.Project{project}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(CLI.Tail.Interval)
+	defer ticker.Stop()
+
+	tail := stats.NewTail(path, project.Name)
+	var total tailTotal
+	if err := startTail(tail, &total); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ticker.C:
+		case <-interrupt:
+			return nil
+		}
+
+		events, err := tail.Poll()
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, e := range events {
+			total.add(e)
+			if err := printTailEvent(e, total); err != nil {
+				return err
+			}
+		}
+
+		// Claude Code starts a new log for each session
+		if latest, _, err := stats.LatestLog(projects); err == nil && latest != tail.Path {
+			tail = stats.NewTail(latest, project.Name)
+			total = tailTotal{}
+			if err := startTail(tail, &total); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// startTail reads what the log already holds into total and prints the
+// header naming it
+func startTail(tail *stats.Tail, total *tailTotal) error {
+	events, err := tail.Poll()
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		total.add(e)
+	}
+
+	// JSON output carries only events, so the header goes to stderr
+	w := os.Stdout
+	if CLI.JSON {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, "Following %s (%s): %d event(s), %s tokens, %s so far\n",
+		tail.Project, filepath.Base(tail.Path), total.events,
+		stats.FormatTokens(total.tokens), stats.FormatCost(total.cost))
+	return nil
+}
+
+// tailTotal is the running total of the followed session
+type tailTotal struct {
+	events int
+	tokens int
+	cost   float64
+}
+
+func (t *tailTotal) add(e stats.UsageEvent) {
+	t.events++
+	t.tokens += e.TotalTokens()
+	t.cost += pricing.EventCost(e)
+}
+
+// printTailEvent prints one event with the session total after it, or the
+// event alone as a line of JSON
+func printTailEvent(e stats.UsageEvent, total tailTotal) error {
+	if CLI.JSON {
+		return json.NewEncoder(os.Stdout).Encode(eventOutput(e))
+	}
+	fmt.Printf("%s  %-12s in %-8s out %-8s cache w %-8s r %-10s %-9s│ session %s tokens, %s\n",
+		e.Timestamp.Local().Format("15:04:05"),
+		stats.ModelName(e.Model),
+		stats.FormatTokens(e.InputTokens),
+		stats.FormatTokens(e.OutputTokens),
+		stats.FormatTokens(e.CacheCreation),
+		stats.FormatTokens(e.CacheRead),
+		stats.FormatCost(pricing.EventCost(e)),
+		stats.FormatTokens(total.tokens),
+		stats.FormatCost(total.cost))
+	return nil
+}
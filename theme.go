@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// theme holds every style the TUI and terminal output draw with, so a
+// palette can be swapped in one place
+type theme struct {
+	App     lipgloss.Style
+	Title   lipgloss.Style
+	Help    lipgloss.Style
+	Warning lipgloss.Style
+	Panel   lipgloss.Style
+
+	// Highlight picks out a key figure among others
+	Highlight lipgloss.Style
+
+	// Selected colors the highlighted list item
+	Selected lipgloss.TerminalColor
+
+	// Gauge colors the limit gauge under 75%, from 75% and from 90%
+	Gauge [3]string
+
+	// ModelFamilies colors model names in usage tables by family, such as
+	// "opus". Mono themes leave them uncolored.
+	ModelFamilies map[string]lipgloss.TerminalColor
+
+	// HeatmapShades are heatmap background colors from least to most
+	// usage. Mono themes have none and draw HeatmapGlyphs instead.
+	HeatmapShades []lipgloss.Color
+	HeatmapGlyphs []string
+}
+
+// themes lists the palettes --theme selects from
+var themes = map[string]func() theme{
+	"dark":  darkTheme,
+	"light": lightTheme,
+	"mono":  monoTheme,
+}
+
+// styles is the theme in use, chosen by --theme before any output
+var styles = darkTheme()
+
+func darkTheme() theme {
+	return theme{
+		App: lipgloss.NewStyle().Padding(1, 2),
+		Title: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FFFDF5")).
+			Background(lipgloss.Color("#7C3AED")).
+			Padding(0, 1),
+		Help:    lipgloss.NewStyle().Foreground(lipgloss.Color("#626262")),
+		Warning: lipgloss.NewStyle().Foreground(lipgloss.Color("#F59E0B")),
+		Panel: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#7C3AED")).
+			Padding(0, 1),
+		Highlight: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#A78BFA")),
+		Selected:  lipgloss.Color("#A78BFA"),
+		Gauge:     [3]string{"#10B981", "#F59E0B", "#EF4444"},
+		ModelFamilies: map[string]lipgloss.TerminalColor{
+			"opus":   lipgloss.Color("#A78BFA"),
+			"sonnet": lipgloss.Color("#60A5FA"),
+			"haiku":  lipgloss.Color("#34D399"),
+		},
+		HeatmapShades: []lipgloss.Color{"#2D2A3A", "#4C3A7A", "#6D4AC0", "#7C3AED", "#A78BFA"},
+	}
+}
+
+// lightTheme darkens the muted and warning colors, and runs the heatmap
+// from pale to deep, for light terminal backgrounds
+func lightTheme() theme {
+	t := darkTheme()
+	t.Help = lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280"))
+	t.Warning = lipgloss.NewStyle().Foreground(lipgloss.Color("#B45309"))
+	t.Highlight = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#6D28D9"))
+	t.Selected = lipgloss.Color("#6D28D9")
+	t.Gauge = [3]string{"#047857", "#B45309", "#B91C1C"}
+	t.ModelFamilies = map[string]lipgloss.TerminalColor{
+		"opus":   lipgloss.Color("#6D28D9"),
+		"sonnet": lipgloss.Color("#1D4ED8"),
+		"haiku":  lipgloss.Color("#047857"),
+	}
+	t.HeatmapShades = []lipgloss.Color{"#F3F4F6", "#DDD6FE", "#A78BFA", "#7C3AED", "#4C1D95"}
+	return t
+}
+
+// monoTheme uses only text attributes, and shade characters for the
+// heatmap, so it reads the same with colors turned off
+func monoTheme() theme {
+	return theme{
+		App:           lipgloss.NewStyle().Padding(1, 2),
+		Title:         lipgloss.NewStyle().Bold(true).Reverse(true).Padding(0, 1),
+		Help:          lipgloss.NewStyle().Faint(true),
+		Warning:       lipgloss.NewStyle().Bold(true),
+		Panel:         lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1),
+		Highlight:     lipgloss.NewStyle().Bold(true),
+		Selected:      lipgloss.NoColor{},
+		HeatmapGlyphs: []string{"··", "░░", "▒▒", "▓▓", "██"},
+	}
+}
+
+// modelColor returns the color for a model's family, matched anywhere in
+// its name, and false for models outside the known families
+func (t theme) modelColor(model string) (lipgloss.TerminalColor, bool) {
+	model = strings.ToLower(model)
+	for family, color := range t.ModelFamilies {
+		if strings.Contains(model, family) {
+			return color, true
+		}
+	}
+	return nil, false
+}
+
+// setTheme selects the named theme. noColor, from --no-color or a set
+// NO_COLOR, drops to mono and strips all styling from output.
+func setTheme(name string, noColor bool) error {
+	newTheme, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q: use dark, light or mono", name)
+	}
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		newTheme = monoTheme
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	styles = newTheme()
+	return nil
+}
+
+// mono reports whether the theme draws without colors
+func (t theme) mono() bool {
+	return len(t.HeatmapShades) == 0
+}
+
+// listDelegate is the default list delegate with the theme's selection
+// color
+func (t theme) listDelegate() list.DefaultDelegate {
+	d := list.NewDefaultDelegate()
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.Foreground(t.Selected).BorderForeground(t.Selected)
+	d.Styles.SelectedDesc = d.Styles.SelectedDesc.Foreground(t.Selected).BorderForeground(t.Selected)
+	if t.mono() {
+		d.Styles.SelectedTitle = d.Styles.SelectedTitle.Bold(true)
+	}
+	return d
+}